@@ -0,0 +1,176 @@
+package eskomlol
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/teamjorge/eskomlol/internal/fileutil"
+)
+
+// defaultCacheTTLs contains how long responses of each endpoint are cached by default.
+var defaultCacheTTLs = map[string]time.Duration{
+	"/GetStatus":         5 * time.Minute,
+	"/GetMunicipalities": 7 * 24 * time.Hour,
+	"/GetSurburbData":    7 * 24 * time.Hour,
+	"/FindSuburbs":       24 * time.Hour,
+	"/GetScheduleM":      12 * time.Hour,
+}
+
+// Cache stores raw responses from the Eskom API.
+//
+// Implementations must be safe for concurrent use. Entries are expired by the Client
+// based on the TTL of the endpoint, so a Cache is not required to expire entries itself.
+type Cache interface {
+	// Get returns the entry stored for key and whether it was found.
+	Get(key string) (CacheEntry, bool)
+	// Set stores the entry for key.
+	Set(key string, entry CacheEntry) error
+}
+
+// CacheEntry is a single response stored in a Cache.
+type CacheEntry struct {
+	Data     []byte    `json:"data"`
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// MemoryCache is an in-memory Cache that evicts the least recently used entries once full.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewMemoryCache creates a MemoryCache holding at most capacity entries.
+//
+// A capacity below 1 is treated as 1.
+func NewMemoryCache(capacity int) *MemoryCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &MemoryCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the entry stored for key and marks it as recently used.
+func (m *MemoryCache) Get(key string) (CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.items[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	m.order.MoveToFront(element)
+
+	return element.Value.(*memoryCacheItem).entry, true
+}
+
+// Set stores the entry for key, evicting the least recently used entry if the cache is full.
+func (m *MemoryCache) Set(key string, entry CacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.Data = append([]byte(nil), entry.Data...)
+
+	if element, ok := m.items[key]; ok {
+		element.Value.(*memoryCacheItem).entry = entry
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.items[key] = m.order.PushFront(&memoryCacheItem{key: key, entry: entry})
+	for m.order.Len() > m.capacity {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.items, oldest.Value.(*memoryCacheItem).key)
+	}
+
+	return nil
+}
+
+// Len returns the number of entries in the cache.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// DiskCache is a Cache storing each entry as a file in a directory.
+//
+// Entries survive restarts, which allows stale responses to be served when the Eskom API
+// is unreachable on startup.
+type DiskCache struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewDiskCache creates a DiskCache in the given directory, creating it if required.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &DiskCache{dir: dir}, nil
+}
+
+// Get returns the entry stored for key. Unreadable entries are treated as missing.
+func (d *DiskCache) Get(key string) (CacheEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data, err := ioutil.ReadFile(d.path(key))
+	if err != nil {
+		return CacheEntry{}, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return CacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// Set writes the entry for key to disk.
+func (d *DiskCache) Set(key string, entry CacheEntry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFileAtomic(d.path(key), data)
+}
+
+func (d *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// cacheTTL returns how long responses of the given endpoint are cached.
+func cacheTTL(c *Client, endpoint string) time.Duration {
+	name := endpointName(endpoint)
+	if ttl, ok := c.cacheTTLs[name]; ok {
+		return ttl
+	}
+	return defaultCacheTTLs[name]
+}
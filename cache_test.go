@@ -0,0 +1,234 @@
+package eskomlol
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	m := NewMemoryCache(2)
+	now := time.Date(2021, 10, 27, 18, 0, 0, 0, time.UTC)
+
+	m.Set("a", CacheEntry{Data: []byte("1"), StoredAt: now})
+	m.Set("b", CacheEntry{Data: []byte("2"), StoredAt: now})
+
+	// Use a so that b becomes the least recently used entry.
+	if entry, ok := m.Get("a"); !ok || string(entry.Data) != "1" {
+		t.Errorf("expected a to be cached with value 1, got %v", entry)
+	}
+
+	m.Set("c", CacheEntry{Data: []byte("3"), StoredAt: now})
+
+	if _, ok := m.Get("b"); ok {
+		t.Error("expected b to have been evicted")
+	}
+	if _, ok := m.Get("a"); !ok {
+		t.Error("expected a to still be cached")
+	}
+	if entry, ok := m.Get("c"); !ok || !entry.StoredAt.Equal(now) {
+		t.Errorf("expected c to be cached at %s, got %v", now, entry)
+	}
+	if m.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", m.Len())
+	}
+
+	m.Set("c", CacheEntry{Data: []byte("4"), StoredAt: now})
+	if entry, _ := m.Get("c"); string(entry.Data) != "4" {
+		t.Errorf("expected c to be updated to 4, got %s", string(entry.Data))
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2021, 10, 27, 18, 0, 0, 0, time.UTC)
+
+	d, err := NewDiskCache(dir)
+	if err != nil {
+		t.Errorf("unexpected error creating disk cache: %v", err)
+		return
+	}
+
+	if _, ok := d.Get("http://eskom/GetStatus"); ok {
+		t.Error("did not expect an entry in an empty cache")
+	}
+
+	if err := d.Set("http://eskom/GetStatus", CacheEntry{Data: []byte("2"), StoredAt: now}); err != nil {
+		t.Errorf("unexpected error setting cache entry: %v", err)
+	}
+
+	// A new DiskCache in the same directory should see the stored entry.
+	d, err = NewDiskCache(dir)
+	if err != nil {
+		t.Errorf("unexpected error creating disk cache: %v", err)
+		return
+	}
+
+	entry, ok := d.Get("http://eskom/GetStatus")
+	if !ok {
+		t.Error("expected entry to be cached")
+		return
+	}
+	if string(entry.Data) != "2" {
+		t.Errorf("expected cached data to be 2, got %s", string(entry.Data))
+	}
+	if !entry.StoredAt.Equal(now) {
+		t.Errorf("expected entry to be stored at %s, got %s", now, entry.StoredAt)
+	}
+}
+
+func TestDoRequestCache(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("2"))
+	}))
+	defer server.Close()
+
+	now := time.Date(2021, 10, 27, 18, 0, 0, 0, time.UTC)
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithCache(NewMemoryCache(10)),
+		withNowFunc(func() time.Time { return now }),
+	)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Status(ctx); err != nil {
+			t.Errorf("did not expect an error when calling Status, got: %v", err)
+		}
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("expected 1 request while the status is cached, got %d", atomic.LoadInt32(&calls))
+	}
+
+	now = now.Add(defaultCacheTTLs["/GetStatus"])
+	if _, err := c.Status(ctx); err != nil {
+		t.Errorf("did not expect an error when calling Status, got: %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("expected 2 requests once the status expired, got %d", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestDoRequestCacheTTLOverride(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("2"))
+	}))
+	defer server.Close()
+
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithCache(NewMemoryCache(10)),
+		WithCacheTTL("/GetStatus", 0),
+	)
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Status(context.Background()); err != nil {
+			t.Errorf("did not expect an error when calling Status, got: %v", err)
+		}
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("expected 3 requests with caching disabled, got %d", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestDoRequestStaleOnError(t *testing.T) {
+	var down int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("3"))
+	}))
+	defer server.Close()
+
+	now := time.Date(2021, 10, 27, 18, 0, 0, 0, time.UTC)
+	opts := []ClientOpt{
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithCache(NewMemoryCache(10)),
+		withNowFunc(func() time.Time { return now }),
+	}
+	ctx := context.Background()
+
+	c := New(append(opts, WithStaleOnError(true))...)
+	if _, err := c.Status(ctx); err != nil {
+		t.Errorf("did not expect an error when calling Status, got: %v", err)
+	}
+
+	atomic.StoreInt32(&down, 1)
+	now = now.Add(time.Hour)
+
+	stage, err := c.Status(ctx)
+	if err != nil {
+		t.Errorf("expected the stale status to be served, got: %v", err)
+	}
+	if stage != 2 {
+		t.Errorf("expected stale stage value to be 2, got %d", stage)
+	}
+
+	c = New(opts...)
+	if _, err := c.Status(ctx); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected error to match ErrUnavailable without stale responses, got %v", err)
+	}
+}
+
+func TestDoRequestCacheInvalidResponse(t *testing.T) {
+	var calls int32
+	var maintenance int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&maintenance) == 1 {
+			w.Write([]byte("<html>Down for maintenance</html>"))
+			return
+		}
+		w.Write([]byte("3"))
+	}))
+	defer server.Close()
+
+	now := time.Date(2021, 10, 27, 18, 0, 0, 0, time.UTC)
+	opts := []ClientOpt{
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		withNowFunc(func() time.Time { return now }),
+	}
+	ctx := context.Background()
+
+	// A maintenance page served with a 200 status is not cached.
+	atomic.StoreInt32(&maintenance, 1)
+	c := New(append(opts, WithCache(NewMemoryCache(10)))...)
+	for i := 0; i < 2; i++ {
+		if _, err := c.Status(ctx); err == nil {
+			t.Error("expected an error for the maintenance page")
+		}
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("expected 2 requests when the response is invalid, got %d", atomic.LoadInt32(&calls))
+	}
+
+	atomic.StoreInt32(&maintenance, 0)
+	if stage, err := c.Status(ctx); err != nil || stage != 2 {
+		t.Errorf("expected stage 2 once the API recovered, got %d, %v", stage, err)
+	}
+
+	// With stale responses, the last valid response is served instead of the maintenance page.
+	c = New(append(opts, WithCache(NewMemoryCache(10)), WithStaleOnError(true))...)
+	if _, err := c.Status(ctx); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&maintenance, 1)
+	now = now.Add(time.Hour)
+	if stage, err := c.Status(ctx); err != nil || stage != 2 {
+		t.Errorf("expected the stale stage 2, got %d, %v", stage, err)
+	}
+}
@@ -2,29 +2,52 @@ package eskomlol
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 )
 
+// defaultScheduleConcurrency is the default number of stages fetched concurrently by Schedule.
+const defaultScheduleConcurrency = 4
+
+// suburbPageSize is the number of suburbs requested per page from /GetSurburbData.
+const suburbPageSize = 100
+
 // Client is the structure for performing requests to the Eskom API.
 type Client struct {
 	timeout    time.Duration
 	httpClient HttpClient
+	baseURL    string
+	userAgent  string
 	nowFunc    func() time.Time
+	location   *time.Location
+
+	retryPolicy          RetryPolicy
+	rateLimiter          *RateLimiter
+	endpointRateLimiters map[string]*RateLimiter
+	cache                Cache
+	cacheTTLs            map[string]time.Duration
+	staleOnError         bool
+	scheduleConcurrency  int
+	requestObserver      func(endpoint string, duration time.Duration, err error)
 }
 
 // New creates an instance of the Client with the given options.
 //
-// The timeout is set by default to 30 seconds and can be overridden with the withTimeout option.
+// The timeout is set by default to 30 seconds and can be overridden with the WithTimeout option.
+// Requests are sent to the public Eskom API unless a different base URL is given with WithBaseURL.
+// Schedules are parsed in the Africa/Johannesburg time zone, which can be changed with WithLocation.
 func New(opts ...ClientOpt) *Client {
 	c := new(Client)
 	c.timeout = 30 * time.Second
 	c.nowFunc = time.Now
+	c.location = loadLocation("Africa/Johannesburg")
 	c.httpClient = nil
+	c.baseURL = defaultBaseURL
+	c.userAgent = defaultUserAgent
+	c.scheduleConcurrency = defaultScheduleConcurrency
 
 	for _, opt := range opts {
 		opt(c)
@@ -37,14 +60,11 @@ func New(opts ...ClientOpt) *Client {
 //
 // Values of -1 and 0 indicate no loadshedding currently.
 func (c *Client) Status(ctx context.Context) (Stage, error) {
-	h := getClient(c)
-
-	data, err := doRequest(ctx, h, "/GetStatus", nil)
-	if err != nil {
-		return -1, err
-	}
-
-	status, err := strconv.Atoi(string(data))
+	var status int
+	_, err := doRequest(ctx, c, "/GetStatus", nil, func(data []byte) (err error) {
+		status, err = strconv.Atoi(string(data))
+		return err
+	})
 	if err != nil {
 		return -1, err
 	}
@@ -54,12 +74,10 @@ func (c *Client) Status(ctx context.Context) (Stage, error) {
 
 // Municipalities returns a list of municipalities that Eskom supplies to.
 func (c *Client) Municipalities(ctx context.Context, province Province) (Municipalities, error) {
-	h := getClient(c)
-
 	requestURL := fmt.Sprintf("/GetMunicipalities/?Id=%d", province)
 	var municipalities Municipalities
 
-	err := doRequestJSON(ctx, h, requestURL, nil, &municipalities)
+	err := doRequestJSON(ctx, c, requestURL, nil, &municipalities)
 
 	return municipalities, err
 }
@@ -67,18 +85,18 @@ func (c *Client) Municipalities(ctx context.Context, province Province) (Municip
 // Suburbs returns a list of suburbs from the given municipality and search term.
 //
 // The responses are paginated and can be iterated by using the page parameter. The result
-// object contains a Total field to indicate the total number of results.
+// object contains a Total field to indicate the total number of results. IterateSuburbs and
+// AllSuburbs walk every page.
 func (c *Client) Suburbs(ctx context.Context, municipalityID string, searchTerm string, page int) (SuburbResult, error) {
-	h := getClient(c)
 	if page < 1 {
 		page = 1
 	}
 	requestURL := fmt.Sprintf(
-		"/GetSurburbData/?pageSize=100&pageNum=%d&searchTerm=%s&id=%s",
-		page, url.QueryEscape(searchTerm), url.QueryEscape(municipalityID),
+		"/GetSurburbData/?pageSize=%d&pageNum=%d&searchTerm=%s&id=%s",
+		suburbPageSize, page, url.QueryEscape(searchTerm), url.QueryEscape(municipalityID),
 	)
 	var suburbResult SuburbResult
-	err := doRequestJSON(ctx, h, requestURL, nil, &suburbResult)
+	err := doRequestJSON(ctx, c, requestURL, nil, &suburbResult)
 
 	return suburbResult, err
 }
@@ -87,7 +105,6 @@ func (c *Client) Suburbs(ctx context.Context, municipalityID string, searchTerm
 //
 // maxResults can be omitted (nil) if the default of 300 is acceptable.
 func (c *Client) SearchSuburbs(ctx context.Context, searchTerm string, maxResults *int) (SearchSuburbs, error) {
-	h := getClient(c)
 	maxRes := 300
 	if maxResults != nil {
 		maxRes = *maxResults
@@ -98,42 +115,79 @@ func (c *Client) SearchSuburbs(ctx context.Context, searchTerm string, maxResult
 		url.QueryEscape(searchTerm), maxRes,
 	)
 	var searchSuburbs SearchSuburbs
-	err := doRequestJSON(ctx, h, requestURL, nil, &searchSuburbs)
+	err := doRequestJSON(ctx, c, requestURL, nil, &searchSuburbs)
 
 	return searchSuburbs, err
 }
 
 // Schedule returns the loadshedding schedule for the given suburb and stage(s).
+//
+// Stages are fetched concurrently, limited by the WithScheduleConcurrency option. Schedules of
+// stages that were fetched successfully are returned alongside a *ScheduleError describing any
+// failed stages.
 func (c *Client) Schedule(ctx context.Context, suburbID string, stages ...Stage) (map[Stage]Schedule, error) {
-	h := getClient(c)
-	errs := make([]string, 0)
-	res := make(map[Stage]Schedule)
+	errs := make([]error, len(stages))
+	schedules := make([]*Schedule, len(stages))
 
-	for _, stage := range stages {
-		if !stage.Valid() {
-			errs = append(errs, fmt.Sprintf("%d is not a valid stage", stage))
-			continue
-		}
-		if stage < 1 {
-			errs = append(errs, "only Stages 1 - 8 are valid for schedules")
-			continue
-		}
-		requestURL := fmt.Sprintf(`/GetScheduleM/%s/%d/_/1`, suburbID, stage)
-		data, err := doRequest(ctx, h, requestURL, nil)
-		if err != nil {
-			errs = append(errs, err.Error())
+	concurrency := c.scheduleConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, stage := range stages {
+		if !stage.Valid() || stage < 1 {
+			errs[i] = fmt.Errorf("%w: only Stages 1 - 8 are valid for schedules", ErrInvalidStage)
 			continue
 		}
-		s, err := scheduleFromHTML(data, stage, c.nowFunc())
-		if err != nil {
-			errs = append(errs, err.Error())
+
+		wg.Add(1)
+		go func(i int, stage Stage) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			s, err := c.scheduleForStage(ctx, suburbID, stage)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			schedules[i] = &s
+		}(i, stage)
+	}
+	wg.Wait()
+
+	res := make(map[Stage]Schedule)
+	failed := make(map[Stage]error)
+	for i, stage := range stages {
+		if errs[i] != nil {
+			failed[stage] = errs[i]
 			continue
 		}
-		res[stage] = s
+		res[stage] = *schedules[i]
 	}
-	var err error
-	if len(errs) > 0 {
-		err = errors.New(strings.Join(errs, "; "))
+
+	if len(failed) > 0 {
+		return res, &ScheduleError{Errors: failed}
 	}
-	return res, err
+	return res, nil
+}
+
+// scheduleForStage fetches and parses the schedule of a single stage.
+func (c *Client) scheduleForStage(ctx context.Context, suburbID string, stage Stage) (Schedule, error) {
+	requestURL := fmt.Sprintf(`/GetScheduleM/%s/%d/_/1`, url.PathEscape(suburbID), stage)
+	var schedule Schedule
+	_, err := doRequest(ctx, c, requestURL, nil, func(data []byte) (err error) {
+		schedule, err = scheduleFromHTML(data, stage, c.nowFunc(), c.location)
+		return err
+	})
+
+	return schedule, err
 }
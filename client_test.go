@@ -3,10 +3,13 @@ package eskomlol
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -21,17 +24,17 @@ type clientMockHTTPClient struct {
 
 func (m *clientMockHTTPClient) mapResponses(url string) []byte {
 	return map[string][]byte{
-		baseURL + "/GetStatus":               m.StatusResponse,
-		baseURL + "/GetMunicipalities/?Id=1": m.MunicipalitiesResponse,
-		baseURL + "/GetSurburbData/?pageSize=100&pageNum=1&searchTerm=bryanston&id=1": m.SuburbsResponse,
-		baseURL + "/FindSuburbs?searchText=bryanston&maxResults=300":                  m.SearchSuburbsResponse,
-		baseURL + "/GetScheduleM/1/1/_/1":                                             m.ScheduleResponse,
+		defaultBaseURL + "/GetStatus":                                                        m.StatusResponse,
+		defaultBaseURL + "/GetMunicipalities/?Id=1":                                          m.MunicipalitiesResponse,
+		defaultBaseURL + "/GetSurburbData/?pageSize=100&pageNum=1&searchTerm=bryanston&id=1": m.SuburbsResponse,
+		defaultBaseURL + "/FindSuburbs?searchText=bryanston&maxResults=300":                  m.SearchSuburbsResponse,
+		defaultBaseURL + "/GetScheduleM/1/1/_/1":                                             m.ScheduleResponse,
 	}[url]
 }
 
 func (m *clientMockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	responseData := m.mapResponses(req.URL.String())
-	res := http.Response{}
+	res := http.Response{StatusCode: http.StatusOK}
 
 	buf := bytes.NewBuffer(responseData)
 
@@ -49,6 +52,9 @@ func TestNewClient(t *testing.T) {
 	if !nowFuncDate.After(time.Time{}) {
 		t.Errorf("expected default nowFunc to return a date after nil value. got: %v", nowFuncDate)
 	}
+	if c.location == nil {
+		t.Error("expected a default location")
+	}
 
 	// With Opts
 	c = New(WithTimeout(40 * time.Second))
@@ -58,7 +64,7 @@ func TestNewClient(t *testing.T) {
 }
 
 func TestStatus(t *testing.T) {
-	c := New(withHTTPClient(&clientMockHTTPClient{
+	c := New(WithHTTPClient(&clientMockHTTPClient{
 		StatusResponse: []byte("2"),
 	}))
 	stage, err := c.Status(context.Background())
@@ -72,7 +78,7 @@ func TestStatus(t *testing.T) {
 }
 
 func TestStatusError(t *testing.T) {
-	c := New(withHTTPClient(&clientMockHTTPClient{
+	c := New(WithHTTPClient(&clientMockHTTPClient{
 		StatusResponse: []byte("asd"),
 	}))
 	stage, err := c.Status(context.Background())
@@ -92,7 +98,7 @@ func TestStatusError(t *testing.T) {
 }
 
 func TestMunicipalities(t *testing.T) {
-	c := New(withHTTPClient(&clientMockHTTPClient{
+	c := New(WithHTTPClient(&clientMockHTTPClient{
 		MunicipalitiesResponse: []byte(`
 		[
 			{
@@ -124,7 +130,7 @@ func TestMunicipalities(t *testing.T) {
 }
 
 func TestSuburbs(t *testing.T) {
-	c := New(withHTTPClient(&clientMockHTTPClient{
+	c := New(WithHTTPClient(&clientMockHTTPClient{
 		SuburbsResponse: []byte(`
 		{
 			"Results": [
@@ -163,7 +169,7 @@ func TestSuburbs(t *testing.T) {
 }
 
 func TestSearchSuburbs(t *testing.T) {
-	c := New(withHTTPClient(&clientMockHTTPClient{
+	c := New(WithHTTPClient(&clientMockHTTPClient{
 		SearchSuburbsResponse: []byte(`
 		[
 			{
@@ -225,7 +231,7 @@ func TestSchedule(t *testing.T) {
 		return
 	}
 
-	c := New(withHTTPClient(&clientMockHTTPClient{
+	c := New(WithHTTPClient(&clientMockHTTPClient{
 		ScheduleResponse: []byte(testData),
 	}), withNowFunc(func() time.Time {
 		return time.Date(2021, 10, 27, 18, 00, 00, 0, loc)
@@ -250,7 +256,7 @@ func TestSchedule(t *testing.T) {
 				{Start: time.Date(2021, 11, 07, 00, 0, 0, 0, loc), End: time.Date(2021, 11, 07, 02, 30, 00, 0, loc)},
 				{Start: time.Date(2021, 11, 8, 8, 0, 0, 0, loc), End: time.Date(2021, 11, 8, 10, 30, 00, 0, loc)},
 				{Start: time.Date(2021, 11, 9, 14, 0, 0, 0, loc), End: time.Date(2021, 11, 9, 16, 30, 00, 0, loc)},
-				{Start: time.Date(2021, 11, 10, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 10, 00, 30, 00, 0, loc)},
+				{Start: time.Date(2021, 11, 10, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 11, 00, 30, 00, 0, loc)},
 				{Start: time.Date(2021, 11, 12, 06, 0, 0, 0, loc), End: time.Date(2021, 11, 12, 8, 30, 00, 0, loc)},
 				{Start: time.Date(2021, 11, 13, 12, 0, 0, 0, loc), End: time.Date(2021, 11, 13, 14, 30, 00, 0, loc)},
 				{Start: time.Date(2021, 11, 14, 20, 0, 0, 0, loc), End: time.Date(2021, 11, 14, 22, 30, 00, 0, loc)},
@@ -281,3 +287,223 @@ func TestSchedule(t *testing.T) {
 		}
 	}
 }
+
+func TestClientWithServer(t *testing.T) {
+	scheduleData, err := ioutil.ReadFile("./test_data/schedule.html")
+	if err != nil {
+		t.Errorf("unexpected error reading test file: %v", err)
+		return
+	}
+
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		switch r.URL.Path {
+		case "/LoadShedding/GetStatus":
+			w.Write([]byte("3"))
+		case "/LoadShedding/GetMunicipalities/":
+			w.Write([]byte(`[{"Value": "1", "Text": "Blah"}]`))
+		case "/LoadShedding/GetSurburbData/":
+			w.Write([]byte(`{"Results": [{"id": "1", "text": "Bryanston", "Tot": 4}], "Total": 1}`))
+		case "/LoadShedding/FindSuburbs":
+			w.Write([]byte(`[{"Name": "Bryanston", "Id": 992, "Total": 555}]`))
+		case "/LoadShedding/GetScheduleM/1/1/_/1":
+			w.Write(scheduleData)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := New(
+		WithBaseURL(server.URL+"/LoadShedding/"),
+		WithHTTPClient(server.Client()),
+		WithUserAgent("eskomlol-test"),
+	)
+	ctx := context.Background()
+
+	stage, err := c.Status(ctx)
+	if err != nil {
+		t.Errorf("did not expect an error when calling Status, got: %v", err)
+	}
+	if stage != 2 {
+		t.Errorf("expected stage value to be 2, got %d", stage)
+	}
+
+	municipalities, err := c.Municipalities(ctx, Gauteng)
+	if err != nil {
+		t.Errorf("did not expect an error when calling Municipalities, got: %v", err)
+	}
+	if len(municipalities) != 1 || municipalities[0].Name != "Blah" {
+		t.Errorf("expected a single municipality named Blah, got %v", municipalities)
+	}
+
+	suburbResult, err := c.Suburbs(ctx, "1", "bryanston", 1)
+	if err != nil {
+		t.Errorf("did not expect an error when calling Suburbs, got: %v", err)
+	}
+	if suburbResult.Total != 1 || suburbResult.Results[0].Name != "Bryanston" {
+		t.Errorf("expected a single suburb named Bryanston, got %v", suburbResult)
+	}
+
+	suburbs, err := c.SearchSuburbs(ctx, "bryanston", nil)
+	if err != nil {
+		t.Errorf("did not expect an error when calling SearchSuburbs, got: %v", err)
+	}
+	if len(suburbs) != 1 || suburbs[0].ID != 992 {
+		t.Errorf("expected a single suburb with ID 992, got %v", suburbs)
+	}
+
+	schedule, err := c.Schedule(ctx, "1", 1)
+	if err != nil {
+		t.Errorf("did not expect an error when calling Schedule, got: %v", err)
+	}
+	if len(schedule[1].Times) != 22 {
+		t.Errorf("expected 22 schedule times, got %d", len(schedule[1].Times))
+	}
+
+	for _, userAgent := range userAgents {
+		if userAgent != "eskomlol-test" {
+			t.Errorf("expected User-Agent header to be eskomlol-test, got %s", userAgent)
+		}
+	}
+	if len(userAgents) != 5 {
+		t.Errorf("expected 5 requests to the server, got %d", len(userAgents))
+	}
+}
+
+func TestScheduleConcurrency(t *testing.T) {
+	scheduleData, err := ioutil.ReadFile("./test_data/schedule.html")
+	if err != nil {
+		t.Errorf("unexpected error reading test file: %v", err)
+		return
+	}
+
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write(scheduleData)
+	}))
+	defer server.Close()
+
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithScheduleConcurrency(3),
+	)
+
+	schedule, err := c.Schedule(context.Background(), "1", 1, 2, 3, 4, 5, 6, 7, 8, 9, 0)
+	var scheduleErr *ScheduleError
+	if !errors.As(err, &scheduleErr) {
+		t.Errorf("expected a ScheduleError for the invalid stages, got: %v", err)
+	} else if stages := scheduleErr.Stages(); len(stages) != 2 || stages[0] != 0 || stages[1] != 9 {
+		t.Errorf("expected errors for stages 0 and 9 only, got: %v", err)
+	}
+
+	if len(schedule) != 8 {
+		t.Errorf("expected 8 schedules, got %d", len(schedule))
+	}
+	for stage := Stage(1); stage <= 8; stage++ {
+		if schedule[stage].Stage != stage {
+			t.Errorf("expected schedule of stage %d to have stage %d, got %d", stage, stage, schedule[stage].Stage)
+		}
+	}
+
+	if max := atomic.LoadInt32(&maxInFlight); max > 3 || max < 2 {
+		t.Errorf("expected between 2 and 3 concurrent requests, got %d", max)
+	}
+}
+
+func TestScheduleContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithScheduleConcurrency(2),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	schedule, err := c.Schedule(ctx, "1", 1, 2, 3, 4)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to match context.DeadlineExceeded, got: %v", err)
+	}
+	if len(schedule) != 0 {
+		t.Errorf("expected no schedules, got %d", len(schedule))
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("expected outstanding requests to be cancelled with the context")
+	}
+}
+
+func TestScheduleEscapesSuburbID(t *testing.T) {
+	var path, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.EscapedPath(), r.URL.RawQuery
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := New(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	c.Schedule(context.Background(), "1?x=1", 1)
+
+	if path != "/GetScheduleM/1%3Fx=1/1/_/1" || query != "" {
+		t.Errorf("expected the suburb ID to be escaped, got path %q and query %q", path, query)
+	}
+}
+
+func TestSchedulePartial(t *testing.T) {
+	scheduleData, err := ioutil.ReadFile("./test_data/schedule.html")
+	if err != nil {
+		t.Errorf("unexpected error reading test file: %v", err)
+		return
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/GetScheduleM/1/1/_/1":
+			w.Write(scheduleData)
+		case "/GetScheduleM/1/2/_/1":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`<div class="scheduleDay"><div class="dayMonth">Mon, 01 Nov</div><a>whenever</a></div>`))
+		}
+	}))
+	defer server.Close()
+
+	c := New(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	schedule, err := c.Schedule(context.Background(), "1", 1, 2, 3)
+	if len(schedule) != 1 || len(schedule[1].Times) != 22 {
+		t.Errorf("expected only the schedule of stage 1, got %v", schedule)
+	}
+
+	var scheduleErr *ScheduleError
+	if !errors.As(err, &scheduleErr) {
+		t.Errorf("expected a ScheduleError, got: %v", err)
+		return
+	}
+	if !errors.Is(scheduleErr.Errors[2], ErrUnavailable) {
+		t.Errorf("expected stage 2 to fail with ErrUnavailable, got: %v", scheduleErr.Errors[2])
+	}
+	if !errors.Is(scheduleErr.Errors[3], ErrParse) {
+		t.Errorf("expected stage 3 to fail with ErrParse, got: %v", scheduleErr.Errors[3])
+	}
+}
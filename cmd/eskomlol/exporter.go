@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+	"github.com/teamjorge/eskomlol/exporter"
+)
+
+// suburbFlags collects repeated --suburb flags in the form id or id=name.
+type suburbFlags []exporter.Suburb
+
+func (s *suburbFlags) String() string {
+	parts := make([]string, 0, len(*s))
+	for _, suburb := range *s {
+		parts = append(parts, suburb.ID+"="+suburb.Name)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s *suburbFlags) Set(value string) error {
+	id, name := value, ""
+	if i := strings.Index(value, "="); i >= 0 {
+		id, name = value[:i], value[i+1:]
+	}
+	if strings.TrimSpace(id) == "" {
+		return fmt.Errorf("invalid suburb %q", value)
+	}
+
+	*s = append(*s, exporter.Suburb{ID: strings.TrimSpace(id), Name: strings.TrimSpace(name)})
+	return nil
+}
+
+func exporterCommand(ctx context.Context, fs *flag.FlagSet, args []string, e *env) error {
+	addr := fs.String("addr", ":9090", "address to listen on")
+	interval := fs.Duration("interval", time.Minute, "refresh interval")
+	var suburbs suburbFlags
+	fs.Var(&suburbs, "suburb", "suburb to publish outage metrics for, as id or id=name (repeatable)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("%w: exporter does not accept arguments", errUsage)
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("%w: --interval must be positive", errUsage)
+	}
+
+	opts := []exporter.Opt{exporter.WithRefreshInterval(*interval)}
+	for _, suburb := range suburbs {
+		opts = append(opts, exporter.WithSuburb(suburb.ID, suburb.Name))
+	}
+	exp := exporter.New(opts...)
+	c := e.client(
+		eskomlol.WithCache(eskomlol.NewMemoryCache(100)),
+		eskomlol.WithStaleOnError(true),
+		eskomlol.WithRequestObserver(exp.ObserveRequest),
+	)
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		exp.Run(ctx, c)
+	}()
+	defer func() { <-refreshed }()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exp)
+	return serve(ctx, listener, mux, e)
+}
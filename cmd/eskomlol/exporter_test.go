@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+func TestSuburbFlags(t *testing.T) {
+	var suburbs suburbFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.Var(&suburbs, "suburb", "")
+
+	if err := fs.Parse([]string{"--suburb", "1058852=Bryanston", "--suburb", " 42 "}); err != nil {
+		t.Fatal(err)
+	}
+	expected := suburbFlags{{ID: "1058852", Name: "Bryanston"}, {ID: "42"}}
+	if len(suburbs) != 2 || suburbs[0] != expected[0] || suburbs[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, suburbs)
+	}
+	if suburbs.String() != "1058852=Bryanston,42=" {
+		t.Errorf("unexpected string %q", suburbs.String())
+	}
+
+	if err := fs.Parse([]string{"--suburb", "=Nowhere"}); err == nil {
+		t.Error("expected an error for a suburb without ID")
+	}
+
+	if code, _, _ := runTest(t, "exporter", "--suburb", "=Nowhere"); code != exitUsage {
+		t.Errorf("expected exit code %d, got %d", exitUsage, code)
+	}
+	if code, _, _ := runTest(t, "exporter", "--interval", "0"); code != exitUsage {
+		t.Errorf("expected exit code %d for a zero interval, got %d", exitUsage, code)
+	}
+}
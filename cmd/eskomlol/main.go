@@ -0,0 +1,398 @@
+// Command eskomlol queries the Eskom loadshedding API from the command line.
+//
+// Usage:
+//
+//	eskomlol <command> [flags]
+//
+// Commands:
+//
+//	status                                      current loadshedding stage
+//	provinces                                   list of provinces
+//	municipalities --province <province>        municipalities of a province
+//	suburbs --municipality <id> [--search term] suburbs of a municipality (--all for every page)
+//	search <term>                               search all suburbs
+//	schedule <suburb-id> [--stage 1,2]          loadshedding schedule of a suburb
+//	serve [--addr :8080]                        caching JSON API server
+//	exporter [--addr :9090] [--suburb id=name]  Prometheus exporter
+//
+// Every command accepts --output table|json|csv (and ics for schedule), --base-url and --timeout.
+// Responses can be recorded as fixtures with --record and replayed offline with --replay.
+//
+// The exit code is 0 on success, 1 when a request fails, 2 for invalid usage and 3 when
+// only part of a schedule could be retrieved.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+)
+
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitUsage   = 2
+	exitPartial = 3
+)
+
+const usage = `Usage: eskomlol <command> [flags]
+
+Commands:
+  status                                       current loadshedding stage
+  provinces                                    list of provinces
+  municipalities --province <province>         municipalities of a province
+  suburbs --municipality <id> [--search term]  suburbs of a municipality (--all for every page)
+  search <term>                                search all suburbs
+  schedule <suburb-id> [--stage 1,2]           loadshedding schedule of a suburb
+  serve [--addr :8080]                         caching JSON API server
+  exporter [--addr :9090] [--suburb id=name]   Prometheus exporter
+
+Common flags:
+  --output table|json|csv|ics  output format (ics is only supported by schedule)
+  --base-url <url>             base URL of the Eskom API
+  --timeout <duration>         request timeout (default 30s)
+  --record <dir>               record responses as fixtures in dir
+  --replay <dir>               replay fixtures from dir instead of sending requests
+`
+
+// errUsage indicates that the command was invoked incorrectly.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// env holds the state shared by the commands. The client is created after the flags of a
+// command were parsed, so that it can be configured by the common flags.
+type env struct {
+	out    *output
+	stderr io.Writer
+	client func(opts ...eskomlol.ClientOpt) *eskomlol.Client
+}
+
+// command is a single subcommand of the CLI.
+type command func(ctx context.Context, fs *flag.FlagSet, args []string, e *env) error
+
+var commands = map[string]command{
+	"status":         statusCommand,
+	"provinces":      provincesCommand,
+	"municipalities": municipalitiesCommand,
+	"suburbs":        suburbsCommand,
+	"search":         searchCommand,
+	"schedule":       scheduleCommand,
+	"serve":          serveCommand,
+	"exporter":       exporterCommand,
+}
+
+// run executes the CLI with the given arguments and returns the exit code.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("eskomlol "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := &formatFlag{value: "table", formats: []string{"table", "json", "csv"}}
+	if args[0] == "schedule" {
+		format.formats = append(format.formats, "ics")
+	}
+	fs.Var(format, "output", "output format: "+strings.Join(format.formats, ", "))
+	baseURL := fs.String("base-url", "", "base URL of the Eskom API")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	record := fs.String("record", "", "directory to record request and response fixtures in")
+	replay := fs.String("replay", "", "directory to replay recorded fixtures from, instead of sending requests")
+
+	e := &env{
+		out:    &output{w: stdout, format: format.String},
+		stderr: stderr,
+		client: func(extra ...eskomlol.ClientOpt) *eskomlol.Client {
+			opts := []eskomlol.ClientOpt{eskomlol.WithTimeout(*timeout)}
+			if *baseURL != "" {
+				opts = append(opts, eskomlol.WithBaseURL(*baseURL))
+			}
+			switch {
+			case *replay != "":
+				opts = append(opts, eskomlol.WithHTTPClient(eskomlol.NewRecorder(*replay, eskomlol.Replay, nil)))
+			case *record != "":
+				httpClient := &http.Client{Timeout: *timeout}
+				opts = append(opts, eskomlol.WithHTTPClient(eskomlol.NewRecorder(*record, eskomlol.Record, httpClient)))
+			}
+			return eskomlol.New(append(opts, extra...)...)
+		},
+	}
+
+	err := cmd(ctx, fs, args[1:], e)
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitUsage
+	case errors.Is(err, errPartial):
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitPartial
+	default:
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+}
+
+// errPartial indicates that only part of the requested data could be retrieved.
+var errPartial = errors.New("partial result")
+
+// parseFlags parses the flags of a command, allowing flags to appear after positional
+// arguments, and returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	return positional, nil
+}
+
+func statusCommand(ctx context.Context, fs *flag.FlagSet, args []string, e *env) error {
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	stage, err := e.client().Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.out.write(result{
+		headers: []string{"Stage"},
+		rows:    [][]string{{stage.Name()}},
+		value:   map[string]eskomlol.Stage{"stage": stage},
+	})
+}
+
+func provincesCommand(ctx context.Context, fs *flag.FlagSet, args []string, e *env) error {
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	type province struct {
+		ID   int               `json:"id"`
+		Name eskomlol.Province `json:"name"`
+	}
+
+	res := result{headers: []string{"ID", "Name"}}
+	provinces := make([]province, 0)
+	for _, p := range eskomlol.Provinces() {
+		res.rows = append(res.rows, []string{strconv.Itoa(int(p)), p.Name()})
+		provinces = append(provinces, province{ID: int(p), Name: p})
+	}
+	res.value = provinces
+
+	return e.out.write(res)
+}
+
+func municipalitiesCommand(ctx context.Context, fs *flag.FlagSet, args []string, e *env) error {
+	provinceFlag := fs.String("province", "", "province name or number")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	province, err := eskomlol.ParseProvince(*provinceFlag)
+	if err != nil {
+		return fmt.Errorf("%w: --province: %v", errUsage, err)
+	}
+
+	municipalities, err := e.client().Municipalities(ctx, province)
+	if err != nil {
+		return err
+	}
+
+	res := result{headers: []string{"ID", "Name"}, value: municipalities}
+	for _, m := range municipalities {
+		res.rows = append(res.rows, []string{m.ID, m.Name})
+	}
+
+	return e.out.write(res)
+}
+
+func suburbsCommand(ctx context.Context, fs *flag.FlagSet, args []string, e *env) error {
+	municipality := fs.String("municipality", "", "municipality ID")
+	search := fs.String("search", "", "search term")
+	page := fs.Int("page", 1, "page of results")
+	all := fs.Bool("all", false, "retrieve every page of results")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *municipality == "" {
+		return fmt.Errorf("%w: --municipality is required", errUsage)
+	}
+
+	var suburbResult eskomlol.SuburbResult
+	var err error
+	if *all {
+		suburbResult.Results, err = e.client().AllSuburbs(ctx, *municipality, *search, 4)
+		suburbResult.Total = len(suburbResult.Results)
+	} else {
+		suburbResult, err = e.client().Suburbs(ctx, *municipality, *search, *page)
+	}
+	if err != nil {
+		return err
+	}
+
+	res := result{headers: []string{"ID", "Name", "Total"}, value: suburbResult}
+	for _, s := range suburbResult.Results {
+		res.rows = append(res.rows, []string{s.ID, s.Name, strconv.Itoa(s.Total)})
+	}
+
+	return e.out.write(res)
+}
+
+func searchCommand(ctx context.Context, fs *flag.FlagSet, args []string, e *env) error {
+	maxResults := fs.Int("max", 300, "maximum number of results")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+		return fmt.Errorf("%w: search requires a search term", errUsage)
+	}
+
+	suburbs, err := e.client().SearchSuburbs(ctx, strings.Join(positional, " "), maxResults)
+	if err != nil {
+		return err
+	}
+
+	res := result{headers: []string{"ID", "Name", "Municipality", "Province", "Total"}, value: suburbs}
+	for _, s := range suburbs {
+		res.rows = append(res.rows, []string{strconv.Itoa(s.ID), s.Name, s.MunicipalityName, s.ProvinceName, strconv.Itoa(s.Total)})
+	}
+
+	return e.out.write(res)
+}
+
+func scheduleCommand(ctx context.Context, fs *flag.FlagSet, args []string, e *env) error {
+	stagesFlag := fs.String("stage", "", "comma separated stages (default is the current stage)")
+	name := fs.String("name", "", "suburb name used in ics summaries")
+	alarm := fs.Duration("alarm", 0, "ics reminder before each outage")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return fmt.Errorf("%w: schedule requires a single suburb ID", errUsage)
+	}
+	suburbID := positional[0]
+
+	stages, err := parseStages(*stagesFlag)
+	if err != nil {
+		return err
+	}
+
+	c := e.client()
+	if len(stages) == 0 {
+		stage, err := c.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if stage < 1 {
+			return fmt.Errorf("%w: there is currently no loadshedding, provide --stage", errUsage)
+		}
+		stages = []eskomlol.Stage{stage}
+	}
+
+	schedules, scheduleErr := c.Schedule(ctx, suburbID, stages...)
+	if len(schedules) == 0 && scheduleErr != nil {
+		return scheduleErr
+	}
+
+	if e.out.format() == "ics" {
+		enc := eskomlol.NewICalEncoder(e.out.w)
+		enc.SuburbID = suburbID
+		enc.SuburbName = *name
+		if *alarm > 0 {
+			enc.Alarms = []time.Duration{*alarm}
+		}
+		err = enc.EncodeMap(schedules)
+	} else {
+		res := result{headers: []string{"Stage", "Start", "End", "Feeders"}, value: schedules}
+		for _, stage := range stages {
+			s, ok := schedules[stage]
+			if !ok {
+				continue
+			}
+			for _, item := range s.Times {
+				res.rows = append(res.rows, []string{
+					stage.Name(),
+					e.out.formatTime(item.Start),
+					e.out.formatTime(item.End),
+					strings.Join(item.Feeders, "; "),
+				})
+			}
+		}
+		err = e.out.write(res)
+	}
+	if err != nil {
+		return err
+	}
+
+	if scheduleErr != nil {
+		return fmt.Errorf("%w: %v", errPartial, scheduleErr)
+	}
+	return nil
+}
+
+// parseStages parses a comma separated list of stages.
+func parseStages(value string) ([]eskomlol.Stage, error) {
+	stages := make([]eskomlol.Stage, 0)
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		stage, err := eskomlol.ParseStage(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: --stage: %v", errUsage, err)
+		}
+		if stage < 1 {
+			return nil, fmt.Errorf("%w: --stage: %q has no schedule, expected a stage from 1 to 8", errUsage, strings.TrimSpace(part))
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
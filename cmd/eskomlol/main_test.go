@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/eskomlol/eskomtest"
+)
+
+func newTestServer(t *testing.T) *eskomtest.Server {
+	t.Helper()
+
+	server := eskomtest.NewServer(eskomtest.DefaultDataset(time.Now()))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func runTest(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	return runTestServer(t, newTestServer(t), args...)
+}
+
+func runTestServer(t *testing.T, server *eskomtest.Server, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	args = append(args, "--base-url", server.URL)
+	code := run(context.Background(), args, &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunStatus(t *testing.T) {
+	code, stdout, stderr := runTest(t, "status")
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "Stage 2") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	code, stdout, _ = runTest(t, "status", "--output", "json")
+	if code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	if strings.TrimSpace(stdout) != "{\n  \"stage\": \"Stage 2\"\n}" {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+}
+
+func TestRunProvinces(t *testing.T) {
+	code, stdout, _ := runTest(t, "provinces", "--output", "csv")
+	if code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 10 {
+		t.Fatalf("expected header and 9 provinces, got %d records", len(records))
+	}
+	if records[9][0] != "9" || records[9][1] != "Western Cape" {
+		t.Errorf("unexpected last province: %v", records[9])
+	}
+}
+
+func TestRunMunicipalities(t *testing.T) {
+	code, stdout, stderr := runTest(t, "municipalities", "--province", "western-cape")
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "Stellenbosch") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	if code, _, _ := runTest(t, "municipalities", "--province", "atlantis"); code != exitUsage {
+		t.Errorf("expected exit code %d for invalid province, got %d", exitUsage, code)
+	}
+}
+
+func TestRunSuburbs(t *testing.T) {
+	code, stdout, _ := runTest(t, "suburbs", "--municipality", "166", "--search", "bry", "--output", "json")
+	if code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	if !strings.Contains(stdout, `"text": "Bryanston"`) {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	code, stdout, _ = runTest(t, "suburbs", "--municipality", "167", "--all", "--output", "csv")
+	if code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	if stdout != "ID,Name,Total\n1060001,Hatfield,310\n" {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	if code, _, _ := runTest(t, "suburbs"); code != exitUsage {
+		t.Errorf("expected exit code %d without municipality, got %d", exitUsage, code)
+	}
+}
+
+func TestRunSearch(t *testing.T) {
+	code, stdout, _ := runTest(t, "search", "bryanston")
+	if code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	if !strings.Contains(stdout, "1058852") || !strings.Contains(stdout, "Gauteng") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	if code, _, _ := runTest(t, "search"); code != exitUsage {
+		t.Errorf("expected exit code %d without term, got %d", exitUsage, code)
+	}
+}
+
+func TestRunSchedule(t *testing.T) {
+	code, stdout, stderr := runTest(t, "schedule", "1058852", "--stage", "1,2", "--output", "json")
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr)
+	}
+	var schedules map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(stdout), &schedules); err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules) != 2 {
+		t.Errorf("expected 2 schedules, got %d", len(schedules))
+	}
+
+	code, stdout, _ = runTest(t, "schedule", "1058852", "--stage", "1", "--output", "ics", "--name", "Bryanston")
+	if code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	if !strings.HasPrefix(stdout, "BEGIN:VCALENDAR") || !strings.Contains(stdout, "Bryanston") {
+		t.Errorf("unexpected ics output: %q", stdout)
+	}
+
+	// Stage 3 fails and only part of the schedule is returned.
+	server := newTestServer(t)
+	server.FailWhen(eskomtest.Schedule, http.StatusNotFound, func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, "/GetScheduleM/1058852/3/")
+	})
+	code, stdout, _ = runTestServer(t, server, "schedule", "1058852", "--stage", "1,3", "--output", "csv")
+	if code != exitPartial {
+		t.Errorf("expected exit code %d, got %d", exitPartial, code)
+	}
+	if !strings.HasPrefix(stdout, "Stage,Start,End,Feeders\nStage 1,") {
+		t.Errorf("unexpected csv output: %q", stdout)
+	}
+
+	if code, _, _ := runTest(t, "schedule", "1058852", "--stage", "9"); code != exitUsage {
+		t.Errorf("expected exit code %d for invalid stage, got %d", exitUsage, code)
+	}
+}
+
+func TestRunRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	code, stdout, _ := runTest(t, "schedule", "1058852", "--stage", "1", "--output", "json", "--record", dir)
+	if code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+
+	var replayed bytes.Buffer
+	code = run(context.Background(), []string{"schedule", "1058852", "--stage", "1", "--output", "json", "--replay", dir, "--base-url", "http://eskom.invalid"}, &replayed, ioutil.Discard)
+	if code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	if replayed.String() != stdout {
+		t.Errorf("expected the replayed output to match the recording:\n%s\n%s", replayed.String(), stdout)
+	}
+
+	code = run(context.Background(), []string{"status", "--replay", dir, "--base-url", "http://eskom.invalid"}, ioutil.Discard, ioutil.Discard)
+	if code != exitError {
+		t.Errorf("expected exit code %d without a fixture, got %d", exitError, code)
+	}
+}
+
+func TestRunOutputValidatedBeforeRequest(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, format := range []string{"ics", "xml"} {
+		code, _, stderr := runTestServer(t, server, "status", "--output", format)
+		if code != exitUsage {
+			t.Errorf("%s: expected exit code %d, got %d", format, exitUsage, code)
+		}
+		if !strings.Contains(stderr, "unsupported output format") {
+			t.Errorf("%s: unexpected stderr %q", format, stderr)
+		}
+	}
+	if n := server.Requests(eskomtest.Status); n != 0 {
+		t.Errorf("expected no requests for unsupported output formats, got %d", n)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no command", nil, exitUsage},
+		{"unknown command", []string{"nope"}, exitUsage},
+		{"unknown flag", []string{"status", "--nope"}, exitUsage},
+		{"unsupported output", []string{"provinces", "--output", "xml"}, exitUsage},
+		{"ics output", []string{"status", "--output", "ics"}, exitUsage},
+		{"stage 0", []string{"schedule", "1058852", "--stage", "0"}, exitUsage},
+		{"negative stage", []string{"schedule", "1058852", "--stage", "-1"}, exitUsage},
+		{"no loadshedding stage", []string{"schedule", "1058852", "--stage", "none"}, exitUsage},
+		{"unknown stage", []string{"schedule", "1058852", "--stage", "1,unknown"}, exitUsage},
+		{"request failure", []string{"schedule", "2", "--stage", "1"}, exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, _ := runTest(t, tt.args...); code != tt.code {
+				t.Errorf("expected exit code %d, got %d", tt.code, code)
+			}
+		})
+	}
+}
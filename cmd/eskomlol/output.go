@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// result is the output of a command, as rows for table and csv output and as a value for json output.
+type result struct {
+	headers []string
+	rows    [][]string
+	value   interface{}
+}
+
+// formatFlag is the value of --output, restricted to the formats supported by the command so that
+// unsupported formats are rejected before any request is sent.
+type formatFlag struct {
+	value   string
+	formats []string
+}
+
+func (f *formatFlag) String() string {
+	return f.value
+}
+
+func (f *formatFlag) Set(value string) error {
+	for _, format := range f.formats {
+		if value == format {
+			f.value = value
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format %q, expected one of %s", value, strings.Join(f.formats, ", "))
+}
+
+// output writes results in the format selected with --output.
+type output struct {
+	w      io.Writer
+	format func() string
+}
+
+func (o *output) write(res result) error {
+	switch o.format() {
+	case "table":
+		tw := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(res.headers, "\t"))
+		for _, row := range res.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(o.w)
+		if err := cw.Write(res.headers); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(res.rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res.value)
+	default:
+		return fmt.Errorf("%w: unsupported output format %q", errUsage, o.format())
+	}
+}
+
+// formatTime formats a time for the selected output format.
+func (o *output) formatTime(t time.Time) string {
+	if o.format() == "table" {
+		return t.Format("Mon 2006-01-02 15:04")
+	}
+	return t.Format(time.RFC3339)
+}
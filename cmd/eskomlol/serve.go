@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+	"github.com/teamjorge/eskomlol/server"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+func serveCommand(ctx context.Context, fs *flag.FlagSet, args []string, e *env) error {
+	addr := fs.String("addr", ":8080", "address to listen on")
+	cacheSize := fs.Int("cache-size", 1000, "number of responses cached in memory")
+	cacheDir := fs.String("cache-dir", "", "directory to cache responses in, instead of memory")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("%w: serve does not accept arguments", errUsage)
+	}
+
+	var cache eskomlol.Cache = eskomlol.NewMemoryCache(*cacheSize)
+	if *cacheDir != "" {
+		if cache, err = eskomlol.NewDiskCache(*cacheDir); err != nil {
+			return err
+		}
+	}
+	c := e.client(
+		eskomlol.WithCache(cache),
+		eskomlol.WithStaleOnError(true),
+		eskomlol.WithRetryPolicy(eskomlol.DefaultRetryPolicy()),
+	)
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	return serve(ctx, listener, server.New(c), e)
+}
+
+// serve serves handler on the listener until the context is done and then shuts down gracefully.
+func serve(ctx context.Context, listener net.Listener, handler http.Handler, e *env) error {
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(listener)
+	}()
+	fmt.Fprintf(e.stderr, "listening on %s\n", listener.Addr())
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
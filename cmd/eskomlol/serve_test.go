@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+	"github.com/teamjorge/eskomlol/server"
+)
+
+func TestServe(t *testing.T) {
+	upstream := newTestServer(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		handler := server.New(eskomlol.New(eskomlol.WithBaseURL(upstream.URL)))
+		done <- serve(ctx, listener, handler, &env{stderr: ioutil.Discard})
+	}()
+
+	res, err := http.Get("http://" + listener.Addr().String() + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(body), `"stage":"Stage 2"`) {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for shutdown")
+	}
+
+	if code, _, _ := runTest(t, "serve", "extra"); code != exitUsage {
+		t.Errorf("expected exit code %d, got %d", exitUsage, code)
+	}
+}
@@ -0,0 +1,314 @@
+package eskomlol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/teamjorge/eskomlol/internal/fileutil"
+)
+
+// Directory is the tree of every province, municipality and suburb supplied by Eskom, see Crawl.
+type Directory struct {
+	Provinces []DirectoryProvince `json:"provinces"`
+	CrawledAt time.Time           `json:"crawled_at"`
+}
+
+// DirectoryProvince is a province of a Directory.
+type DirectoryProvince struct {
+	Province       Province                `json:"province"`
+	Municipalities []DirectoryMunicipality `json:"municipalities"`
+}
+
+// DirectoryMunicipality is a municipality of a Directory.
+type DirectoryMunicipality struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	Suburbs Suburbs `json:"suburbs"`
+}
+
+// CrawlProgress is reported after every municipality of a Crawl.
+type CrawlProgress struct {
+	Province     Province
+	Municipality Municipality
+	// Suburbs is the number of suburbs found in the municipality.
+	Suburbs int
+	// Done is the number of municipalities crawled so far, including failed municipalities.
+	Done int
+	// Total is the number of municipalities to crawl.
+	Total int
+	// Resumed indicates that the municipality was restored from the checkpoint.
+	Resumed bool
+	// Err is the error that stopped the crawl of the municipality, if any.
+	Err error
+}
+
+type crawlConfig struct {
+	provinces   []Province
+	progress    func(CrawlProgress)
+	checkpoint  string
+	limiter     *RateLimiter
+	concurrency int
+}
+
+type CrawlOpt func(*crawlConfig)
+
+// WithCrawlProvinces limits the crawl to the given provinces. All provinces are crawled by default.
+func WithCrawlProvinces(provinces ...Province) CrawlOpt {
+	return func(c *crawlConfig) {
+		c.provinces = provinces
+	}
+}
+
+// WithCrawlProgress sets a callback invoked after every municipality. Calls are not concurrent.
+func WithCrawlProgress(fn func(CrawlProgress)) CrawlOpt {
+	return func(c *crawlConfig) {
+		c.progress = fn
+	}
+}
+
+// WithCrawlCheckpoint stores the progress of the crawl in a JSON file at path. The whole file is
+// rewritten after the municipalities of every province were listed and after every crawled
+// municipality.
+//
+// A crawl with an existing checkpoint skips everything already crawled, so an interrupted or
+// partially failed crawl can be resumed by running it again. Nothing is fetched again once the
+// checkpoint is complete: remove the file, or use a new path, to start a fresh crawl.
+func WithCrawlCheckpoint(path string) CrawlOpt {
+	return func(c *crawlConfig) {
+		c.checkpoint = path
+	}
+}
+
+// WithCrawlRateLimiter waits on the given RateLimiter before every request of the crawl, in
+// addition to any rate limiters of the Client.
+func WithCrawlRateLimiter(l *RateLimiter) CrawlOpt {
+	return func(c *crawlConfig) {
+		c.limiter = l
+	}
+}
+
+// WithCrawlConcurrency sets how many municipalities are crawled concurrently. The default is 1.
+func WithCrawlConcurrency(n int) CrawlOpt {
+	return func(c *crawlConfig) {
+		c.concurrency = n
+	}
+}
+
+// crawlCheckpoint is the progress of a crawl stored by WithCrawlCheckpoint.
+type crawlCheckpoint struct {
+	// Municipalities contains the municipalities of each crawled province.
+	Municipalities map[Province]Municipalities `json:"municipalities"`
+	// Suburbs contains the suburbs of each crawled municipality, keyed by province and municipality ID.
+	Suburbs map[string]Suburbs `json:"suburbs"`
+}
+
+func crawlKey(province Province, municipalityID string) string {
+	return fmt.Sprintf("%d/%s", province, municipalityID)
+}
+
+// Crawl walks every province, its municipalities and all pages of their suburbs and returns
+// the resulting Directory.
+//
+// Branches that fail are left out of the Directory and reported by a *CrawlError, together with
+// everything that was crawled successfully.
+func (c *Client) Crawl(ctx context.Context, opts ...CrawlOpt) (Directory, error) {
+	cfg := crawlConfig{provinces: Provinces(), concurrency: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.concurrency < 1 {
+		cfg.concurrency = 1
+	}
+
+	cp := crawlCheckpoint{
+		Municipalities: make(map[Province]Municipalities),
+		Suburbs:        make(map[string]Suburbs),
+	}
+	if cfg.checkpoint != "" {
+		if err := loadCrawlCheckpoint(cfg.checkpoint, &cp); err != nil {
+			return Directory{}, err
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		failures []CrawlFailure
+	)
+
+	// The municipalities of every province are retrieved first, so that progress can be
+	// reported against the total number of municipalities.
+	type job struct {
+		province     Province
+		municipality Municipality
+	}
+	jobs := make([]job, 0)
+	for _, province := range cfg.provinces {
+		municipalities, ok := cp.Municipalities[province]
+		if !ok {
+			var err error
+			if municipalities, err = c.crawlMunicipalities(ctx, cfg, province); err != nil {
+				failures = append(failures, CrawlFailure{Province: province, Err: err})
+				continue
+			}
+			cp.Municipalities[province] = municipalities
+			if err := saveCrawlCheckpoint(cfg.checkpoint, &cp); err != nil {
+				return Directory{}, err
+			}
+		}
+
+		for _, m := range municipalities {
+			jobs = append(jobs, job{province: province, municipality: m})
+		}
+	}
+
+	done := 0
+	report := func(p CrawlProgress) {
+		done++
+		p.Done, p.Total = done, len(jobs)
+		if cfg.progress != nil {
+			cfg.progress(p)
+		}
+	}
+
+	var checkpointErr error
+	sem := make(chan struct{}, cfg.concurrency)
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		key := crawlKey(j.province, j.municipality.ID)
+		mu.Lock()
+		suburbs, resumed := cp.Suburbs[key]
+		if resumed {
+			report(CrawlProgress{Province: j.province, Municipality: j.municipality, Suburbs: len(suburbs), Resumed: true})
+		}
+		mu.Unlock()
+		if resumed {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			mu.Lock()
+			failures = append(failures, CrawlFailure{Province: j.province, MunicipalityID: j.municipality.ID, Err: ctx.Err()})
+			report(CrawlProgress{Province: j.province, Municipality: j.municipality, Err: ctx.Err()})
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(j job, key string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			it := c.IterateSuburbs(j.municipality.ID, "")
+			it.limiter = cfg.limiter
+			suburbs := make(Suburbs, 0)
+			for it.Next(ctx) {
+				suburbs = append(suburbs, it.Suburb())
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err := it.Err(); err != nil {
+				failures = append(failures, CrawlFailure{Province: j.province, MunicipalityID: j.municipality.ID, Err: err})
+				report(CrawlProgress{Province: j.province, Municipality: j.municipality, Err: err})
+				return
+			}
+
+			cp.Suburbs[key] = suburbs
+			if err := saveCrawlCheckpoint(cfg.checkpoint, &cp); err != nil && checkpointErr == nil {
+				checkpointErr = err
+			}
+			report(CrawlProgress{Province: j.province, Municipality: j.municipality, Suburbs: len(suburbs)})
+		}(j, key)
+	}
+	wg.Wait()
+
+	if checkpointErr != nil {
+		return Directory{}, checkpointErr
+	}
+
+	dir := Directory{Provinces: make([]DirectoryProvince, 0, len(cfg.provinces)), CrawledAt: c.nowFunc()}
+	for _, province := range cfg.provinces {
+		municipalities, ok := cp.Municipalities[province]
+		if !ok {
+			continue
+		}
+
+		p := DirectoryProvince{Province: province, Municipalities: make([]DirectoryMunicipality, 0, len(municipalities))}
+		for _, m := range municipalities {
+			suburbs, ok := cp.Suburbs[crawlKey(province, m.ID)]
+			if !ok {
+				continue
+			}
+			p.Municipalities = append(p.Municipalities, DirectoryMunicipality{ID: m.ID, Name: m.Name, Suburbs: suburbs})
+		}
+		dir.Provinces = append(dir.Provinces, p)
+	}
+
+	if len(failures) > 0 {
+		positions := make(map[Province]int, len(cfg.provinces))
+		for i, province := range cfg.provinces {
+			positions[province] = i
+		}
+		sort.SliceStable(failures, func(i, j int) bool {
+			if failures[i].Province != failures[j].Province {
+				return positions[failures[i].Province] < positions[failures[j].Province]
+			}
+			return failures[i].MunicipalityID < failures[j].MunicipalityID
+		})
+		return dir, &CrawlError{Failures: failures}
+	}
+	return dir, nil
+}
+
+func (c *Client) crawlMunicipalities(ctx context.Context, cfg crawlConfig, province Province) (Municipalities, error) {
+	if cfg.limiter != nil {
+		if err := cfg.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return c.Municipalities(ctx, province)
+}
+
+func loadCrawlCheckpoint(path string, cp *crawlCheckpoint) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return fmt.Errorf("invalid crawl checkpoint %s: %v", path, err)
+	}
+
+	if cp.Municipalities == nil {
+		cp.Municipalities = make(map[Province]Municipalities)
+	}
+	if cp.Suburbs == nil {
+		cp.Suburbs = make(map[string]Suburbs)
+	}
+	return nil
+}
+
+func saveCrawlCheckpoint(path string, cp *crawlCheckpoint) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFileAtomic(path, data)
+}
@@ -0,0 +1,188 @@
+package eskomlol_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/teamjorge/eskomlol"
+	"github.com/teamjorge/eskomlol/eskomtest"
+)
+
+// newCrawlServer serves two municipalities for Gauteng, one for the Western Cape and none for
+// the other provinces. Municipality 10 has 150 suburbs, the others have 2.
+func newCrawlServer(t *testing.T) *eskomtest.Server {
+	t.Helper()
+
+	data := eskomtest.Dataset{
+		Municipalities: map[eskomlol.Province][]eskomtest.Municipality{
+			eskomlol.Gauteng:     {{ID: "10", Name: "City of Johannesburg"}, {ID: "11", Name: "City of Tshwane"}},
+			eskomlol.WesternCape: {{ID: "20", Name: "Stellenbosch"}},
+		},
+		Suburbs: map[string][]eskomtest.Suburb{},
+	}
+	for id, total := range map[string]int{"10": 150, "11": 2, "20": 2} {
+		for i := 0; i < total; i++ {
+			data.Suburbs[id] = append(data.Suburbs[id], eskomtest.Suburb{ID: id + "-" + strconv.Itoa(i), Name: "Suburb " + strconv.Itoa(i)})
+		}
+	}
+	server := eskomtest.NewServer(data)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// crawlRequests returns the number of requests a crawl sent to the server.
+func crawlRequests(server *eskomtest.Server) int {
+	return server.Requests(eskomtest.Municipalities) + server.Requests(eskomtest.Suburbs)
+}
+
+func TestCrawl(t *testing.T) {
+	server := newCrawlServer(t)
+	c := server.NewClient()
+
+	var progress []eskomlol.CrawlProgress
+	limiter := eskomlol.NewRateLimiter(0, 1)
+	dir, err := c.Crawl(context.Background(),
+		eskomlol.WithCrawlProgress(func(p eskomlol.CrawlProgress) { progress = append(progress, p) }),
+		eskomlol.WithCrawlRateLimiter(limiter),
+		eskomlol.WithCrawlConcurrency(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dir.Provinces) != 9 {
+		t.Fatalf("expected 9 provinces, got %d", len(dir.Provinces))
+	}
+	var gauteng, westernCape eskomlol.DirectoryProvince
+	for _, p := range dir.Provinces {
+		switch p.Province {
+		case eskomlol.Gauteng:
+			gauteng = p
+		case eskomlol.WesternCape:
+			westernCape = p
+		}
+	}
+	if len(gauteng.Municipalities) != 2 || gauteng.Municipalities[0].Name != "City of Johannesburg" {
+		t.Fatalf("unexpected Gauteng municipalities %+v", gauteng.Municipalities)
+	}
+	if n := len(gauteng.Municipalities[0].Suburbs); n != 150 {
+		t.Errorf("expected 150 suburbs in Johannesburg, got %d", n)
+	}
+	if len(westernCape.Municipalities) != 1 || len(westernCape.Municipalities[0].Suburbs) != 2 {
+		t.Errorf("unexpected Western Cape municipalities %+v", westernCape.Municipalities)
+	}
+
+	// 9 municipality lists and 4 pages of suburbs.
+	if n := crawlRequests(server); n != 13 {
+		t.Errorf("expected 13 requests, got %d", n)
+	}
+	if stats := limiter.Stats(); stats.Requests != 13 {
+		t.Errorf("expected 13 rate limited requests, got %d", stats.Requests)
+	}
+
+	if len(progress) != 3 {
+		t.Fatalf("expected 3 progress reports, got %d", len(progress))
+	}
+	for i, p := range progress {
+		if p.Done != i+1 || p.Total != 3 || p.Err != nil || p.Resumed {
+			t.Errorf("unexpected progress %+v", p)
+		}
+	}
+}
+
+func TestCrawlCheckpoint(t *testing.T) {
+	server := newCrawlServer(t)
+	server.FailWhen(eskomtest.Municipalities, http.StatusServiceUnavailable, func(r *http.Request) bool {
+		return r.URL.Query().Get("Id") == strconv.Itoa(int(eskomlol.WesternCape))
+	})
+	server.FailWhen(eskomtest.Suburbs, http.StatusServiceUnavailable, func(r *http.Request) bool {
+		return r.URL.Query().Get("id") == "11"
+	})
+	c := server.NewClient()
+	checkpoint := filepath.Join(t.TempDir(), "crawl.json")
+
+	var failedProgress int
+	dir, err := c.Crawl(context.Background(),
+		eskomlol.WithCrawlCheckpoint(checkpoint),
+		eskomlol.WithCrawlProgress(func(p eskomlol.CrawlProgress) {
+			if p.Err != nil {
+				failedProgress++
+			}
+		}),
+	)
+
+	var crawlErr *eskomlol.CrawlError
+	if !errors.As(err, &crawlErr) {
+		t.Fatalf("expected a CrawlError, got %v", err)
+	}
+	if len(crawlErr.Failures) != 2 {
+		t.Fatalf("expected 2 failures, got %+v", crawlErr.Failures)
+	}
+	if f := crawlErr.Failures[0]; f.Province != eskomlol.Gauteng || f.MunicipalityID != "11" {
+		t.Errorf("unexpected first failure %+v", f)
+	}
+	if f := crawlErr.Failures[1]; f.Province != eskomlol.WesternCape || f.MunicipalityID != "" {
+		t.Errorf("unexpected second failure %+v", f)
+	}
+	if !errors.Is(err, eskomlol.ErrUnavailable) {
+		t.Errorf("expected the CrawlError to match ErrUnavailable")
+	}
+	if failedProgress != 1 {
+		t.Errorf("expected 1 failed municipality in progress reports, got %d", failedProgress)
+	}
+	if len(dir.Provinces) != 8 {
+		t.Errorf("expected the 8 crawled provinces, got %d", len(dir.Provinces))
+	}
+
+	// Resuming only requests the failed branches.
+	server.FailWhen(eskomtest.Municipalities, 0, nil)
+	server.FailWhen(eskomtest.Suburbs, 0, nil)
+	before := crawlRequests(server)
+	var resumed int
+	dir, err = c.Crawl(context.Background(),
+		eskomlol.WithCrawlCheckpoint(checkpoint),
+		eskomlol.WithCrawlProgress(func(p eskomlol.CrawlProgress) {
+			if p.Resumed {
+				resumed++
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Western Cape municipalities, its suburbs and the suburbs of Tshwane.
+	if n := crawlRequests(server) - before; n != 3 {
+		t.Errorf("expected 3 requests when resuming, got %d", n)
+	}
+	if resumed != 1 {
+		t.Errorf("expected 1 resumed municipality, got %d", resumed)
+	}
+	total := 0
+	for _, p := range dir.Provinces {
+		for _, m := range p.Municipalities {
+			total += len(m.Suburbs)
+		}
+	}
+	if len(dir.Provinces) != 9 || total != 154 {
+		t.Errorf("expected 9 provinces with 154 suburbs, got %d provinces with %d suburbs", len(dir.Provinces), total)
+	}
+}
+
+func TestCrawlCancel(t *testing.T) {
+	server := newCrawlServer(t)
+	c := server.NewClient()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := c.Crawl(ctx,
+		eskomlol.WithCrawlProvinces(eskomlol.Gauteng),
+		eskomlol.WithCrawlProgress(func(eskomlol.CrawlProgress) { cancel() }),
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
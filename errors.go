@@ -0,0 +1,185 @@
+package eskomlol
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxErrorBodyLength is the maximum number of bytes of a response body kept in an APIError.
+const maxErrorBodyLength = 512
+
+var (
+	// ErrUnavailable indicates that the Eskom API responded with a server error (5xx).
+	ErrUnavailable = errors.New("eskom api is unavailable")
+	// ErrNotFound indicates that the requested Eskom API resource does not exist.
+	ErrNotFound = errors.New("eskom api resource not found")
+	// ErrRateLimited indicates that the Eskom API rejected the request due to rate limiting.
+	ErrRateLimited = errors.New("eskom api rate limit exceeded")
+	// ErrEmptyResponse indicates that the Eskom API responded successfully without a body.
+	ErrEmptyResponse = errors.New("eskom api returned an empty response")
+	// ErrInvalidStage indicates an invalid stage, such as a schedule requested for a stage other
+	// than 1 - 8 or a value that could not be parsed as a Stage.
+	ErrInvalidStage = errors.New("invalid stage")
+	// ErrInvalidProvince indicates that a value could not be parsed as a Province.
+	ErrInvalidProvince = errors.New("invalid province")
+	// ErrParse indicates that a response of the Eskom API could not be parsed.
+	ErrParse = errors.New("eskom api response could not be parsed")
+)
+
+// APIError is returned when the Eskom API responds with a non-successful status code.
+//
+// The sentinel errors ErrUnavailable, ErrNotFound and ErrRateLimited can be matched
+// against an APIError with errors.Is.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Endpoint is the API endpoint that was requested, excluding the base URL.
+	Endpoint string
+	// Body contains the response body, truncated to 512 bytes.
+	Body string
+	// RetryAfter is the delay requested by the Retry-After header, if present.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("eskom api request to %s failed with status %d", e.Endpoint, e.StatusCode)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// RetryAfterDelay returns RetryAfter, implementing RetryAfterError.
+func (e *APIError) RetryAfterDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// Unwrap returns the sentinel error matching the status code, if any.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+// newAPIError creates an APIError, truncating the body if required.
+func newAPIError(statusCode int, endpoint string, body []byte) *APIError {
+	truncated := string(body)
+	if len(body) > maxErrorBodyLength {
+		truncated = string(body[:maxErrorBodyLength]) + "..."
+	}
+
+	return &APIError{
+		StatusCode: statusCode,
+		Endpoint:   endpoint,
+		Body:       truncated,
+	}
+}
+
+// ScheduleError is returned by Client.Schedule when the schedules of one or more stages
+// could not be retrieved.
+//
+// The schedules of the remaining stages are still returned, which allows callers to decide
+// whether partial results are acceptable. errors.Is and errors.As match against the error of
+// any failed stage.
+type ScheduleError struct {
+	// Errors maps each failed Stage to its underlying error.
+	Errors map[Stage]error
+}
+
+// Error implements the error interface.
+func (e *ScheduleError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, stage := range e.Stages() {
+		msgs = append(msgs, fmt.Sprintf("stage %d: %v", stage, e.Errors[stage]))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Stages returns the failed stages in ascending order.
+func (e *ScheduleError) Stages() []Stage {
+	stages := make([]Stage, 0, len(e.Errors))
+	for stage := range e.Errors {
+		stages = append(stages, stage)
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i] < stages[j] })
+	return stages
+}
+
+// Is reports whether the error of any failed stage matches target.
+func (e *ScheduleError) Is(target error) bool {
+	for _, stage := range e.Stages() {
+		if errors.Is(e.Errors[stage], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of the failed stages, in ascending order, that matches target.
+func (e *ScheduleError) As(target interface{}) bool {
+	for _, stage := range e.Stages() {
+		if errors.As(e.Errors[stage], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// CrawlFailure is a branch of the directory that could not be crawled.
+type CrawlFailure struct {
+	Province Province
+	// MunicipalityID is empty when the municipalities of the province could not be retrieved.
+	MunicipalityID string
+	Err            error
+}
+
+// CrawlError is returned by Crawl when some branches of the directory could not be crawled.
+type CrawlError struct {
+	// Failures contains the failed branches, ordered by province and municipality.
+	Failures []CrawlFailure
+}
+
+// Error implements the error interface.
+func (e *CrawlError) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		if f.MunicipalityID == "" {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", f.Province.Name(), f.Err))
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("%s municipality %s: %v", f.Province.Name(), f.MunicipalityID, f.Err))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether the error of any failed branch matches target.
+func (e *CrawlError) Is(target error) bool {
+	for _, f := range e.Failures {
+		if errors.Is(f.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of the failed branches that matches target.
+func (e *CrawlError) As(target interface{}) bool {
+	for _, f := range e.Failures {
+		if errors.As(f.Err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package eskomlol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		target     error
+	}{
+		{statusCode: 404, target: ErrNotFound},
+		{statusCode: 429, target: ErrRateLimited},
+		{statusCode: 500, target: ErrUnavailable},
+		{statusCode: 503, target: ErrUnavailable},
+	}
+
+	for _, test := range tests {
+		var err error = newAPIError(test.statusCode, "/GetStatus", nil)
+		if !errors.Is(err, test.target) {
+			t.Errorf("expected status %d to match %v", test.statusCode, test.target)
+		}
+	}
+
+	var err error = newAPIError(400, "/GetStatus", nil)
+	for _, target := range []error{ErrNotFound, ErrRateLimited, ErrUnavailable} {
+		if errors.Is(err, target) {
+			t.Errorf("did not expect status 400 to match %v", target)
+		}
+	}
+}
+
+func TestAPIErrorAs(t *testing.T) {
+	var err error = newAPIError(503, "/GetStatus", []byte("down for maintenance"))
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Error("expected error to be an APIError")
+		return
+	}
+
+	if apiErr.StatusCode != 503 {
+		t.Errorf("expected status code to be 503, got %d", apiErr.StatusCode)
+	}
+	if apiErr.Endpoint != "/GetStatus" {
+		t.Errorf("expected endpoint to be /GetStatus, got %s", apiErr.Endpoint)
+	}
+
+	expectedErr := "eskom api request to /GetStatus failed with status 503: down for maintenance"
+	if err.Error() != expectedErr {
+		t.Errorf("expected err value to be %s, got %s", expectedErr, err.Error())
+	}
+}
+
+func TestAPIErrorTruncation(t *testing.T) {
+	body := []byte(strings.Repeat("a", maxErrorBodyLength+10))
+	apiErr := newAPIError(500, "/GetStatus", body)
+
+	if len(apiErr.Body) != maxErrorBodyLength+3 {
+		t.Errorf("expected body to be truncated to %d characters, got %d", maxErrorBodyLength+3, len(apiErr.Body))
+	}
+	if !strings.HasSuffix(apiErr.Body, "...") {
+		t.Errorf("expected truncated body to end with ..., got %s", apiErr.Body)
+	}
+}
+
+func TestScheduleError(t *testing.T) {
+	var err error = &ScheduleError{Errors: map[Stage]error{
+		4: newAPIError(503, "/GetScheduleM/1/4/_/1", nil),
+		9: fmt.Errorf("%w: only Stages 1 - 8 are valid for schedules", ErrInvalidStage),
+		2: fmt.Errorf("%w: bad html", ErrParse),
+	}}
+
+	expectedErr := "stage 2: eskom api response could not be parsed: bad html; " +
+		"stage 4: eskom api request to /GetScheduleM/1/4/_/1 failed with status 503; " +
+		"stage 9: invalid stage: only Stages 1 - 8 are valid for schedules"
+	if err.Error() != expectedErr {
+		t.Errorf("expected err value to be %s, got %s", expectedErr, err.Error())
+	}
+
+	for _, target := range []error{ErrInvalidStage, ErrParse, ErrUnavailable} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected error to match %v", target)
+		}
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("did not expect error to match ErrNotFound")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Error("expected error to contain an APIError")
+	} else if apiErr.StatusCode != 503 {
+		t.Errorf("expected status code to be 503, got %d", apiErr.StatusCode)
+	}
+
+	var scheduleErr *ScheduleError
+	if !errors.As(err, &scheduleErr) {
+		t.Error("expected error to be a ScheduleError")
+	} else if !errors.Is(scheduleErr.Errors[9], ErrInvalidStage) {
+		t.Errorf("expected stage 9 to have failed with ErrInvalidStage, got %v", scheduleErr.Errors[9])
+	}
+}
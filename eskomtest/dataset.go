@@ -0,0 +1,120 @@
+package eskomtest
+
+import (
+	"sort"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+)
+
+// Dataset is the data served by a Server.
+type Dataset struct {
+	// Stage is the loadshedding stage reported by /GetStatus, unless changed with SetStage, SetStageAt or SetStageSequence.
+	Stage eskomlol.Stage
+	// Municipalities contains the municipalities of each province.
+	Municipalities map[eskomlol.Province][]Municipality
+	// Suburbs contains the suburbs of each municipality, keyed by municipality ID.
+	Suburbs map[string][]Suburb
+	// Schedules contains the outages of each suburb per stage, keyed by suburb ID.
+	Schedules map[string]map[eskomlol.Stage][]eskomlol.ScheduleItem
+}
+
+// Municipality is a municipality of a Dataset.
+type Municipality struct {
+	ID   string
+	Name string
+}
+
+// Suburb is a suburb of a Dataset.
+type Suburb struct {
+	ID   string
+	Name string
+	// Feeder is shown on the schedule page of the suburb.
+	Feeder string
+	// Total is the number of customers reported for the suburb.
+	Total int
+}
+
+// DefaultDataset returns a small Dataset with two provinces, three municipalities and schedules
+// for every stage of the week starting on the day of now.
+func DefaultDataset(now time.Time) Dataset {
+	d := Dataset{
+		Stage: 2,
+		Municipalities: map[eskomlol.Province][]Municipality{
+			eskomlol.Gauteng: {
+				{ID: "166", Name: "City of Johannesburg"},
+				{ID: "167", Name: "City of Tshwane"},
+			},
+			eskomlol.WesternCape: {
+				{ID: "336", Name: "Stellenbosch"},
+			},
+		},
+		Suburbs: map[string][]Suburb{
+			"166": {
+				{ID: "1058852", Name: "Bryanston", Feeder: "BRYNORTH / CHIBBA 1 11kV MV Feeder Underground Cable", Total: 555},
+				{ID: "1058853", Name: "Bryanston Ext 1", Feeder: "BRYNORTH / CHIBBA 2 11kV MV Feeder Underground Cable", Total: 120},
+				{ID: "1058900", Name: "Sandton", Feeder: "SANDTON 3 11kV MV Feeder", Total: 860},
+			},
+			"167": {
+				{ID: "1060001", Name: "Hatfield", Feeder: "HATFIELD 1 11kV MV Feeder", Total: 310},
+			},
+			"336": {
+				{ID: "1070001", Name: "Jamestown", Feeder: "JAMESTOWN 22kV MV Feeder", Total: 42},
+			},
+		},
+		Schedules: make(map[string]map[eskomlol.Stage][]eskomlol.ScheduleItem),
+	}
+
+	// Suburbs are shifted in order of municipality ID, so that every call gives each suburb the
+	// same schedule.
+	ids := make([]string, 0, len(d.Suburbs))
+	for id := range d.Suburbs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	offset := 0
+	for _, id := range ids {
+		for _, suburb := range d.Suburbs[id] {
+			schedules := make(map[eskomlol.Stage][]eskomlol.ScheduleItem)
+			for stage := eskomlol.Stage(1); stage <= 8; stage++ {
+				schedules[stage] = GenerateSchedule(now, 7, stage, offset)
+			}
+			d.Schedules[suburb.ID] = schedules
+			offset++
+		}
+	}
+
+	return d
+}
+
+// slotOffsets are the hours of the first outage of each additional stage, so that every
+// stage includes the outages of the stages below it.
+var slotOffsets = []int{0, 8, 16, 4, 12, 20, 2, 10}
+
+// GenerateSchedule creates a schedule of outages of two and a half hours for the given number
+// of days, starting on the day of start. Higher stages have more outages per day, and outages
+// move two hours later every day. The shift moves all outages by the given number of hours,
+// so that neighbouring suburbs can be given different schedules.
+func GenerateSchedule(start time.Time, days int, stage eskomlol.Stage, shift int) []eskomlol.ScheduleItem {
+	slots := int(stage)
+	if slots > len(slotOffsets) {
+		slots = len(slotOffsets)
+	}
+
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	items := make([]eskomlol.ScheduleItem, 0, days*slots)
+	for d := 0; d < days; d++ {
+		for i := 0; i < slots; i++ {
+			hour := (slotOffsets[i] + 2*d + shift) % 24
+			itemStart := time.Date(day.Year(), day.Month(), day.Day()+d, hour, 0, 0, 0, day.Location())
+			items = append(items, eskomlol.ScheduleItem{
+				Start: itemStart,
+				End:   itemStart.Add(150 * time.Minute),
+			})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Start.Before(items[j].Start) })
+	return items
+}
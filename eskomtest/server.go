@@ -0,0 +1,538 @@
+// Package eskomtest provides a fake Eskom loadshedding API for testing.
+//
+// A Server implements /GetStatus, /GetMunicipalities, /GetSurburbData, /FindSuburbs and
+// /GetScheduleM from an in-memory Dataset, and can inject latency, HTTP errors, malformed
+// responses, overlapping pages and stage changes over time:
+//
+//	srv := eskomtest.NewServer(eskomtest.DefaultDataset(time.Now()))
+//	defer srv.Close()
+//
+//	srv.FailNext("/GetStatus", http.StatusServiceUnavailable, 2)
+//	stage, err := srv.NewClient(eskomlol.WithRetryPolicy(eskomlol.DefaultRetryPolicy())).Status(ctx)
+package eskomtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+)
+
+// Endpoint names accepted by the knobs of a Server.
+const (
+	Status         = "/GetStatus"
+	Municipalities = "/GetMunicipalities"
+	Suburbs        = "/GetSurburbData"
+	FindSuburbs    = "/FindSuburbs"
+	Schedule       = "/GetScheduleM"
+)
+
+// stageChange is a stage reported from a point in time.
+type stageChange struct {
+	at    time.Time
+	stage eskomlol.Stage
+}
+
+// failure is an HTTP error injected for an endpoint. A negative remaining count never runs out.
+type failure struct {
+	status    int
+	remaining int
+}
+
+// matchFailure is an HTTP error injected for the requests of an endpoint selected by match.
+type matchFailure struct {
+	status int
+	match  func(r *http.Request) bool
+}
+
+// Server is a fake Eskom API served by an httptest.Server.
+//
+// All methods are safe to call while requests are being served.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	data          Dataset
+	location      *time.Location
+	nowFunc       func() time.Time
+	latency       time.Duration
+	failures      map[string]failure
+	matchFailures map[string]matchFailure
+	malformed     map[string]bool
+	stages        []stageChange
+	sequence      []eskomlol.Stage
+	pageOverlap   int
+	requests      map[string]int
+	inFlight      int
+	maxInFlight   int
+}
+
+type Opt func(*Server)
+
+// WithNowFunc sets the clock used to select the stage set with SetStageAt.
+func WithNowFunc(nowFunc func() time.Time) Opt {
+	return func(s *Server) {
+		s.nowFunc = nowFunc
+	}
+}
+
+// WithLocation sets the time zone in which schedules are rendered. The default is
+// Africa/Johannesburg (or a fixed UTC+2 zone when the time zone database is unavailable).
+func WithLocation(loc *time.Location) Opt {
+	return func(s *Server) {
+		if loc != nil {
+			s.location = loc
+		}
+	}
+}
+
+// NewServer starts a Server serving the given Dataset. It must be closed with Close.
+func NewServer(data Dataset, opts ...Opt) *Server {
+	s := &Server{
+		data:          data,
+		location:      defaultLocation(),
+		nowFunc:       time.Now,
+		failures:      make(map[string]failure),
+		matchFailures: make(map[string]matchFailure),
+		malformed:     make(map[string]bool),
+		requests:      make(map[string]int),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// NewClient creates an eskomlol.Client sending requests to the Server. Retries are disabled
+// unless enabled with the given options.
+func (s *Server) NewClient(opts ...eskomlol.ClientOpt) *eskomlol.Client {
+	return eskomlol.New(append([]eskomlol.ClientOpt{
+		eskomlol.WithBaseURL(s.URL),
+		eskomlol.WithHTTPClient(s.Client()),
+	}, opts...)...)
+}
+
+// SetLatency delays every response by the given duration.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// SetError makes every request to the endpoint fail with the given status code.
+// A status of zero removes the error.
+func (s *Server) SetError(endpoint string, status int) {
+	s.setFailure(endpoint, status, -1)
+}
+
+// FailNext makes the next n requests to the endpoint fail with the given status code.
+func (s *Server) FailNext(endpoint string, status, n int) {
+	s.setFailure(endpoint, status, n)
+}
+
+func (s *Server) setFailure(endpoint string, status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == 0 || n == 0 {
+		delete(s.failures, endpoint)
+		return
+	}
+	s.failures[endpoint] = failure{status: status, remaining: n}
+}
+
+// FailWhen makes the requests to the endpoint for which match returns true fail with the given
+// status code, such as the requests of a single page or municipality. match is called for every
+// request to the endpoint and must be safe for concurrent use. A status of zero or a nil match
+// removes the error.
+func (s *Server) FailWhen(endpoint string, status int, match func(r *http.Request) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == 0 || match == nil {
+		delete(s.matchFailures, endpoint)
+		return
+	}
+	s.matchFailures[endpoint] = matchFailure{status: status, match: match}
+}
+
+// SetMalformed makes the endpoint respond with a body that can not be parsed.
+func (s *Server) SetMalformed(endpoint string, malformed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.malformed[endpoint] = malformed
+}
+
+// SetStage changes the stage reported by /GetStatus immediately, discarding any stage changes
+// set with SetStageAt or SetStageSequence.
+func (s *Server) SetStage(stage eskomlol.Stage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Stage = stage
+	s.stages = nil
+	s.sequence = nil
+}
+
+// SetStageSequence makes successive /GetStatus requests report the given stages in order, after
+// which the last stage keeps being reported. Failed requests do not advance the sequence, which
+// takes precedence over stage changes set with SetStageAt.
+func (s *Server) SetStageSequence(stages ...eskomlol.Stage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sequence = append([]eskomlol.Stage{}, stages...)
+}
+
+// SetPageOverlap makes every page of /GetSurburbData after the first start with the last n
+// suburbs of the previous page, ahead of its own suburbs, for testing clients against overlapping
+// pages.
+func (s *Server) SetPageOverlap(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageOverlap = n
+}
+
+// SetStageAt changes the stage reported by /GetStatus from the given time onwards, according
+// to the clock set with WithNowFunc.
+func (s *Server) SetStageAt(at time.Time, stage eskomlol.Stage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stages = append(s.stages, stageChange{at: at, stage: stage})
+	sort.SliceStable(s.stages, func(i, j int) bool { return s.stages[i].at.Before(s.stages[j].at) })
+}
+
+// Requests returns the number of requests received by the endpoint, including failed requests.
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+// MaxInFlight returns the highest number of requests to any endpoint served at the same time,
+// including their latency.
+func (s *Server) MaxInFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxInFlight
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint := endpointName(r.URL.Path)
+
+	s.mu.Lock()
+	s.requests[endpoint]++
+	if s.inFlight++; s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	latency := s.latency
+	status := 0
+	if f, ok := s.failures[endpoint]; ok {
+		status = f.status
+		if f.remaining > 0 {
+			if f.remaining--; f.remaining == 0 {
+				delete(s.failures, endpoint)
+			} else {
+				s.failures[endpoint] = f
+			}
+		}
+	}
+	matchFailure, hasMatchFailure := s.matchFailures[endpoint]
+	malformed := s.malformed[endpoint]
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	if status == 0 && hasMatchFailure && matchFailure.match(r) {
+		status = matchFailure.status
+	}
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		}
+	}
+
+	if status != 0 {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "<html><body>%d %s</body></html>", status, http.StatusText(status))
+		return
+	}
+
+	switch endpoint {
+	case Status:
+		if malformed {
+			w.Write([]byte("<html>Service unavailable</html>"))
+			return
+		}
+		s.status(w)
+	case Municipalities, Suburbs, FindSuburbs:
+		if malformed {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Results": [{"id": `))
+			return
+		}
+		s.serveJSON(w, r, endpoint)
+	case Schedule:
+		if malformed {
+			w.Write([]byte(`<div class="scheduleDay"><div class="dayMonth">Someday, 99 Smarch</div><a>all day</a></div>`))
+			return
+		}
+		s.schedule(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) status(w http.ResponseWriter) {
+	s.mu.Lock()
+	stage := s.data.Stage
+	now := s.nowFunc()
+	for _, change := range s.stages {
+		if change.at.After(now) {
+			break
+		}
+		stage = change.stage
+	}
+	if len(s.sequence) > 0 {
+		stage = s.sequence[0]
+		if len(s.sequence) > 1 {
+			s.sequence = s.sequence[1:]
+		}
+	}
+	s.mu.Unlock()
+
+	// The API reports stages offset by one, with -1 for an unknown status.
+	value := int(stage) + 1
+	if stage < 0 {
+		value = -1
+	}
+	w.Write([]byte(strconv.Itoa(value)))
+}
+
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request, endpoint string) {
+	s.mu.Lock()
+	var v interface{}
+	var err error
+	switch endpoint {
+	case Municipalities:
+		v, err = s.municipalities(r)
+	case Suburbs:
+		v, err = s.suburbs(r)
+	default:
+		v, err = s.findSuburbs(r)
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) municipalities(r *http.Request) (interface{}, error) {
+	id, err := strconv.Atoi(r.URL.Query().Get("Id"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid province: %v", err)
+	}
+
+	res := make(eskomlol.Municipalities, 0)
+	for _, m := range s.data.Municipalities[eskomlol.Province(id)] {
+		res = append(res, eskomlol.Municipality{ID: m.ID, Name: m.Name})
+	}
+	return res, nil
+}
+
+func (s *Server) suburbs(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	pageSize, err := strconv.Atoi(query.Get("pageSize"))
+	if err != nil || pageSize < 1 {
+		return nil, fmt.Errorf("invalid pageSize %q", query.Get("pageSize"))
+	}
+	pageNum, err := strconv.Atoi(query.Get("pageNum"))
+	if err != nil || pageNum < 1 {
+		return nil, fmt.Errorf("invalid pageNum %q", query.Get("pageNum"))
+	}
+	term := strings.ToLower(query.Get("searchTerm"))
+
+	matches := make(eskomlol.Suburbs, 0)
+	for _, suburb := range s.data.Suburbs[query.Get("id")] {
+		if strings.Contains(strings.ToLower(suburb.Name), term) {
+			matches = append(matches, eskomlol.Suburb{ID: suburb.ID, Name: suburb.Name, Total: suburb.Total})
+		}
+	}
+
+	res := eskomlol.SuburbResult{Results: make(eskomlol.Suburbs, 0), Total: len(matches)}
+	from, to := (pageNum-1)*pageSize, pageNum*pageSize
+	if pageNum > 1 {
+		if from -= s.pageOverlap; from < 0 {
+			from = 0
+		}
+	}
+	if from < len(matches) {
+		if to > len(matches) {
+			to = len(matches)
+		}
+		res.Results = matches[from:to]
+	}
+	return res, nil
+}
+
+func (s *Server) findSuburbs(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	maxResults, err := strconv.Atoi(query.Get("maxResults"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid maxResults %q", query.Get("maxResults"))
+	}
+	term := strings.ToLower(query.Get("searchText"))
+
+	res := make(eskomlol.SearchSuburbs, 0)
+	for _, province := range eskomlol.Provinces() {
+		for _, m := range s.data.Municipalities[province] {
+			for _, suburb := range s.data.Suburbs[m.ID] {
+				if len(res) >= maxResults || !strings.Contains(strings.ToLower(suburb.Name), term) {
+					continue
+				}
+				id, _ := strconv.Atoi(suburb.ID)
+				res = append(res, eskomlol.SearchSuburb{
+					ID:               id,
+					Name:             suburb.Name,
+					MunicipalityName: m.Name,
+					ProvinceName:     province.Name(),
+					Total:            suburb.Total,
+				})
+			}
+		}
+	}
+	return res, nil
+}
+
+// schedule renders the schedule page of a suburb in the format of the Eskom API,
+// /GetScheduleM/{suburb}/{stage}/_/1.
+func (s *Server) schedule(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 {
+		http.NotFound(w, r)
+		return
+	}
+	stage, err := strconv.Atoi(parts[2])
+	if err != nil {
+		http.Error(w, "invalid stage", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	schedules, ok := s.data.Schedules[parts[1]]
+	items := schedules[eskomlol.Stage(stage)]
+	feeder := s.feeder(parts[1])
+	loc := s.location
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(renderSchedule(items, feeder, loc)))
+}
+
+func (s *Server) feeder(suburbID string) string {
+	for _, suburbs := range s.data.Suburbs {
+		for _, suburb := range suburbs {
+			if suburb.ID == suburbID {
+				return suburb.Feeder
+			}
+		}
+	}
+	return ""
+}
+
+// renderSchedule renders outages like the schedule page of the Eskom API, with a section for
+// every day from the first to the last outage.
+func renderSchedule(items []eskomlol.ScheduleItem, feeder string, loc *time.Location) string {
+	var b strings.Builder
+	b.WriteString("<div class=\"row\">\n<div id=\"schedulem\">\n<div style=\"padding-top:20px\">\n")
+	if feeder != "" {
+		fmt.Fprintf(&b, "<div class=\"areaInfoItem\">\n<span class=\"areaInfoLabel\">Feeder: </span>%s\n</div>\n<br />\n", html.EscapeString(feeder))
+	}
+
+	days := make(map[string][]eskomlol.ScheduleItem)
+	var first, last time.Time
+	for _, item := range items {
+		start := item.Start.In(loc)
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+		key := day.Format("2006-01-02")
+		days[key] = append(days[key], item)
+		if first.IsZero() || day.Before(first) {
+			first = day
+		}
+		if day.After(last) {
+			last = day
+		}
+	}
+
+	for day := first; !first.IsZero() && !day.After(last); day = day.AddDate(0, 0, 1) {
+		fmt.Fprintf(&b, "<div class=\"scheduleDay\">\n<div class=\"dayMonth\">\n%s\n</div>\n<div style=\"padding:10px;\">\n", day.Format("Mon, 02 Jan"))
+
+		dayItems := days[day.Format("2006-01-02")]
+		if len(dayItems) == 0 {
+			b.WriteString("-\n")
+		}
+		for _, item := range dayItems {
+			slot := item.Start.In(loc).Format("15:04") + " - " + item.End.In(loc).Format("15:04")
+			feeders := item.Feeders
+			if len(feeders) == 0 && feeder != "" {
+				feeders = []string{feeder}
+			}
+
+			slots := make([]map[string]string, 0, len(feeders))
+			for _, f := range feeders {
+				slots = append(slots, map[string]string{"Time": slot, "Feeder": f})
+			}
+			data, _ := json.Marshal(slots)
+			// The single quotes delimiting the showFeeder argument must not appear in the JSON.
+			showFeeder := strings.ReplaceAll(string(data), "'", `\u0027`)
+
+			fmt.Fprintf(&b, "<a style=\"text-decoration: none\" onclick=\"showFeeder('%s')\" ;>%s</a> <br>\n", showFeeder, slot)
+		}
+		b.WriteString("</div>\n</div>\n")
+	}
+
+	b.WriteString("</div>\n</div>\n</div>\n")
+	return b.String()
+}
+
+// endpointName returns the name of the API endpoint without any path parameters.
+func endpointName(path string) string {
+	if i := strings.Index(strings.TrimPrefix(path, "/"), "/"); i >= 0 {
+		return path[:i+1]
+	}
+	return path
+}
+
+func defaultLocation() *time.Location {
+	loc, err := time.LoadLocation("Africa/Johannesburg")
+	if err != nil {
+		return time.FixedZone("SAST", 2*60*60)
+	}
+	return loc
+}
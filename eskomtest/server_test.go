@@ -0,0 +1,307 @@
+package eskomtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+	"github.com/teamjorge/eskomlol/eskomtest"
+)
+
+func newServer(t *testing.T, now time.Time, opts ...eskomtest.Opt) *eskomtest.Server {
+	t.Helper()
+	srv := eskomtest.NewServer(eskomtest.DefaultDataset(now), opts...)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestStatus(t *testing.T) {
+	now := time.Now()
+	srv := newServer(t, now)
+	c := srv.NewClient()
+
+	stage, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stage != 2 {
+		t.Errorf("expected stage 2, got %d", stage)
+	}
+
+	srv.SetStage(0)
+	if stage, _ := c.Status(context.Background()); stage != 0 {
+		t.Errorf("expected stage 0, got %d", stage)
+	}
+	srv.SetStage(-1)
+	if stage, _ := c.Status(context.Background()); stage != -1 {
+		t.Errorf("expected stage -1, got %d", stage)
+	}
+}
+
+func TestStageChanges(t *testing.T) {
+	start := time.Date(2021, 11, 10, 12, 0, 0, 0, time.UTC)
+	now := start
+	srv := newServer(t, start, eskomtest.WithNowFunc(func() time.Time { return now }))
+	srv.SetStageAt(start.Add(2*time.Hour), 6)
+	srv.SetStageAt(start.Add(time.Hour), 4)
+	c := srv.NewClient()
+
+	for _, step := range []struct {
+		offset time.Duration
+		stage  eskomlol.Stage
+	}{
+		{0, 2},
+		{time.Hour, 4},
+		{90 * time.Minute, 4},
+		{3 * time.Hour, 6},
+	} {
+		now = start.Add(step.offset)
+		stage, err := c.Status(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stage != step.stage {
+			t.Errorf("after %s: expected stage %d, got %d", step.offset, step.stage, stage)
+		}
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	srv := newServer(t, time.Now())
+	c := srv.NewClient()
+	ctx := context.Background()
+
+	municipalities, err := c.Municipalities(ctx, eskomlol.Gauteng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(municipalities) != 2 || municipalities[0].ID != "166" {
+		t.Errorf("unexpected municipalities %+v", municipalities)
+	}
+
+	suburbs, err := c.Suburbs(ctx, "166", "bryan", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suburbs.Total != 2 || len(suburbs.Results) != 2 || suburbs.Results[0].Name != "Bryanston" {
+		t.Errorf("unexpected suburbs %+v", suburbs)
+	}
+	if suburbs, _ := c.Suburbs(ctx, "166", "bryan", 2); len(suburbs.Results) != 0 || suburbs.Total != 2 {
+		t.Errorf("expected an empty second page, got %+v", suburbs)
+	}
+
+	maxResults := 1
+	results, err := c.SearchSuburbs(ctx, "a", &maxResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected 1 result, got %d", len(results))
+	}
+	results, _ = c.SearchSuburbs(ctx, "jamestown", nil)
+	if len(results) != 1 || results[0].ID != 1070001 || results[0].ProvinceName != "Western Cape" || results[0].MunicipalityName != "Stellenbosch" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	now := time.Now()
+	data := eskomtest.DefaultDataset(now)
+	srv := eskomtest.NewServer(data)
+	defer srv.Close()
+	c := srv.NewClient()
+
+	schedules, err := c.Schedule(context.Background(), "1058852", 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stage := range []eskomlol.Stage{1, 4} {
+		expected := data.Schedules["1058852"][stage]
+		got := schedules[stage].Times
+		if len(got) != len(expected) {
+			t.Fatalf("stage %d: expected %d items, got %d", stage, len(expected), len(got))
+		}
+		for i := range expected {
+			if !got[i].Start.Equal(expected[i].Start) || !got[i].End.Equal(expected[i].End) {
+				t.Errorf("stage %d item %d: expected %s, got %s", stage, i, expected[i], got[i])
+			}
+		}
+		if schedules[stage].Feeder != "BRYNORTH / CHIBBA 1 11kV MV Feeder Underground Cable" {
+			t.Errorf("unexpected feeder %q", schedules[stage].Feeder)
+		}
+	}
+
+	if _, err := c.Schedule(context.Background(), "404", 1); !errors.Is(err, eskomlol.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown suburb, got %v", err)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	srv := newServer(t, time.Now())
+	ctx := context.Background()
+
+	srv.FailNext(eskomtest.Status, http.StatusServiceUnavailable, 2)
+	c := srv.NewClient(eskomlol.WithRetryPolicy(eskomlol.RetryPolicy{MaxAttempts: 3}))
+	if _, err := c.Status(ctx); err != nil {
+		t.Errorf("expected the retries to succeed, got %v", err)
+	}
+	if n := srv.Requests(eskomtest.Status); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	c = srv.NewClient()
+	srv.SetError(eskomtest.Municipalities, http.StatusTooManyRequests)
+	if _, err := c.Municipalities(ctx, eskomlol.Gauteng); !errors.Is(err, eskomlol.ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+	srv.SetError(eskomtest.Municipalities, 0)
+	if _, err := c.Municipalities(ctx, eskomlol.Gauteng); err != nil {
+		t.Errorf("expected the error to be removed, got %v", err)
+	}
+
+	srv.SetMalformed(eskomtest.Suburbs, true)
+	if _, err := c.Suburbs(ctx, "166", "", 1); err == nil {
+		t.Error("expected an error for a malformed body")
+	}
+	srv.SetMalformed(eskomtest.Schedule, true)
+	if _, err := c.Schedule(ctx, "1058852", 1); !errors.Is(err, eskomlol.ErrParse) {
+		t.Errorf("expected ErrParse, got %v", err)
+	}
+	srv.SetMalformed(eskomtest.Status, true)
+	if _, err := c.Status(ctx); err == nil {
+		t.Error("expected an error for a malformed status")
+	}
+
+	srv.SetLatency(50 * time.Millisecond)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Municipalities(timeoutCtx, eskomlol.Gauteng); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestDefaultDatasetDeterministic(t *testing.T) {
+	now := time.Date(2022, 9, 18, 10, 0, 0, 0, time.UTC)
+	expected := eskomtest.DefaultDataset(now).Schedules
+
+	for i := 0; i < 10; i++ {
+		for id, stages := range eskomtest.DefaultDataset(now).Schedules {
+			for stage, items := range stages {
+				if !items[0].Start.Equal(expected[id][stage][0].Start) {
+					t.Fatalf("suburb %s stage %d: expected the first outage at %s, got %s",
+						id, stage, expected[id][stage][0].Start, items[0].Start)
+				}
+			}
+		}
+	}
+
+	// Suburbs are shifted in order of municipality ID.
+	if start := expected["1058852"][1][0].Start; start.Hour() != 0 {
+		t.Errorf("expected the first outage of 1058852 at midnight, got %s", start)
+	}
+	if start := expected["1070001"][1][0].Start; start.Hour() != 4 {
+		t.Errorf("expected the first outage of 1070001 at 04:00, got %s", start)
+	}
+}
+
+func TestStageSequence(t *testing.T) {
+	srv := newServer(t, time.Now())
+	srv.SetStageSequence(3, 4)
+	c := srv.NewClient()
+	ctx := context.Background()
+
+	srv.FailNext(eskomtest.Status, http.StatusInternalServerError, 1)
+	if _, err := c.Status(ctx); err == nil {
+		t.Fatal("expected the injected error")
+	}
+	for i, expected := range []eskomlol.Stage{3, 4, 4} {
+		stage, err := c.Status(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stage != expected {
+			t.Errorf("request %d: expected stage %d, got %d", i, expected, stage)
+		}
+	}
+
+	srv.SetStage(1)
+	if stage, _ := c.Status(ctx); stage != 1 {
+		t.Errorf("expected SetStage to discard the sequence, got stage %d", stage)
+	}
+}
+
+func TestPageOverlap(t *testing.T) {
+	data := eskomtest.Dataset{Suburbs: map[string][]eskomtest.Suburb{"1": nil}}
+	for i := 0; i < 150; i++ {
+		data.Suburbs["1"] = append(data.Suburbs["1"], eskomtest.Suburb{ID: strconv.Itoa(i), Name: "Suburb " + strconv.Itoa(i)})
+	}
+	srv := eskomtest.NewServer(data)
+	defer srv.Close()
+	srv.SetPageOverlap(1)
+	c := srv.NewClient()
+	ctx := context.Background()
+
+	first, err := c.Suburbs(ctx, "1", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Suburbs(ctx, "1", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Results) != 100 || len(second.Results) != 51 || second.Total != 150 {
+		t.Fatalf("expected pages of 100 and 51 suburbs, got %d and %d", len(first.Results), len(second.Results))
+	}
+	if second.Results[0].ID != first.Results[99].ID {
+		t.Errorf("expected the second page to repeat the last suburb of the first, got %+v", second.Results[0])
+	}
+}
+
+func TestFailWhen(t *testing.T) {
+	srv := newServer(t, time.Now())
+	c := srv.NewClient()
+	ctx := context.Background()
+
+	srv.FailWhen(eskomtest.Municipalities, http.StatusNotFound, func(r *http.Request) bool {
+		return r.URL.Query().Get("Id") == "9"
+	})
+	if _, err := c.Municipalities(ctx, eskomlol.WesternCape); !errors.Is(err, eskomlol.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a matching request, got %v", err)
+	}
+	if _, err := c.Municipalities(ctx, eskomlol.Gauteng); err != nil {
+		t.Errorf("expected other requests to succeed, got %v", err)
+	}
+
+	srv.FailWhen(eskomtest.Municipalities, 0, nil)
+	if _, err := c.Municipalities(ctx, eskomlol.WesternCape); err != nil {
+		t.Errorf("expected the error to be removed, got %v", err)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	srv := newServer(t, time.Now())
+	srv.SetLatency(20 * time.Millisecond)
+	c := srv.NewClient()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Status(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := srv.MaxInFlight(); n != 3 {
+		t.Errorf("expected 3 requests in flight, got %d", n)
+	}
+}
@@ -0,0 +1,14 @@
+package eskomlol
+
+import "time"
+
+// Unexported identifiers used by the tests of package eskomlol_test, which cannot import
+// eskomtest from package eskomlol.
+
+var WithNowFunc = withNowFunc
+
+const DefaultWatchInterval = defaultWatchInterval
+
+func (w *Watcher) Delay(failures int) time.Duration {
+	return w.delay(failures)
+}
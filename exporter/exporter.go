@@ -0,0 +1,311 @@
+// Package exporter publishes loadshedding metrics in the Prometheus text exposition format.
+//
+// An Exporter refreshes the stage and the schedules of the configured suburbs in the background
+// and serves the following metrics:
+//
+//	eskom_loadshedding_stage                   current loadshedding stage
+//	eskom_api_request_duration_seconds         histogram of Eskom API request durations by endpoint
+//	eskom_api_errors_total                     failed Eskom API requests by endpoint
+//	eskom_suburb_power_off                     1 when a suburb is currently loadshedding
+//	eskom_suburb_next_outage_timestamp         unix time of the next outage of a suburb
+//	eskom_exporter_last_refresh_success        1 when the last refresh succeeded
+//
+// Request metrics are collected by passing ObserveRequest to eskomlol.WithRequestObserver:
+//
+//	exp := exporter.New(exporter.WithSuburb("1058852", "Bryanston"))
+//	client := eskomlol.New(eskomlol.WithRequestObserver(exp.ObserveRequest))
+//	go exp.Run(ctx, client)
+//	http.Handle("/metrics", exp)
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+)
+
+const defaultRefreshInterval = time.Minute
+
+// DefaultBuckets are the upper bounds in seconds of the request duration histogram.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Suburb is a suburb for which outage metrics are published.
+type Suburb struct {
+	ID   string
+	Name string
+}
+
+// suburbState contains the last known outage state of a suburb.
+type suburbState struct {
+	known      bool
+	powerOff   bool
+	nextOutage time.Time
+}
+
+// histogram is a cumulative histogram of request durations.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Exporter collects loadshedding metrics and serves them over HTTP.
+type Exporter struct {
+	suburbs  []Suburb
+	interval time.Duration
+	buckets  []float64
+	nowFunc  func() time.Time
+
+	mu           sync.Mutex
+	stage        eskomlol.Stage
+	stageKnown   bool
+	refreshOK    bool
+	suburbStates map[string]suburbState
+	durations    map[string]*histogram
+	errors       map[string]uint64
+}
+
+type Opt func(*Exporter)
+
+// WithSuburb publishes outage metrics for the given suburb.
+func WithSuburb(id, name string) Opt {
+	return func(e *Exporter) {
+		e.suburbs = append(e.suburbs, Suburb{ID: id, Name: name})
+	}
+}
+
+// WithRefreshInterval sets how often Run refreshes the metrics. The default is 1 minute.
+//
+// Intervals that are not positive are ignored.
+func WithRefreshInterval(interval time.Duration) Opt {
+	return func(e *Exporter) {
+		if interval > 0 {
+			e.interval = interval
+		}
+	}
+}
+
+// WithBuckets sets the upper bounds of the request duration histogram in seconds.
+func WithBuckets(buckets []float64) Opt {
+	return func(e *Exporter) {
+		e.buckets = append([]float64{}, buckets...)
+		sort.Float64s(e.buckets)
+	}
+}
+
+// New creates an Exporter with the given options.
+func New(opts ...Opt) *Exporter {
+	e := &Exporter{
+		interval:     defaultRefreshInterval,
+		buckets:      DefaultBuckets,
+		nowFunc:      time.Now,
+		suburbStates: make(map[string]suburbState),
+		durations:    make(map[string]*histogram),
+		errors:       make(map[string]uint64),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// ObserveRequest records a request to the Eskom API. It is meant to be passed to
+// eskomlol.WithRequestObserver.
+func (e *Exporter) ObserveRequest(endpoint string, duration time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	h, ok := e.durations[endpoint]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(e.buckets))}
+		e.durations[endpoint] = h
+	}
+	seconds := duration.Seconds()
+	for i, bound := range e.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+
+	if _, ok := e.errors[endpoint]; !ok {
+		e.errors[endpoint] = 0
+	}
+	if err != nil {
+		e.errors[endpoint]++
+	}
+}
+
+// Run refreshes the metrics with the given Client immediately and then on every refresh
+// interval, until the context is done. The context error is returned.
+func (e *Exporter) Run(ctx context.Context, client *eskomlol.Client) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.Refresh(ctx, client)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh retrieves the current stage and the schedules of all suburbs. Metrics of suburbs
+// whose schedule could not be retrieved are removed until a later refresh succeeds, so that an
+// outdated next outage is not reported.
+func (e *Exporter) Refresh(ctx context.Context, client *eskomlol.Client) error {
+	stage, err := client.Status(ctx)
+	if err != nil {
+		// A refresh interrupted by shutting down is not a failure.
+		if ctx.Err() == nil {
+			e.mu.Lock()
+			e.refreshOK = false
+			e.mu.Unlock()
+		}
+		return err
+	}
+
+	now := e.nowFunc()
+	states := make(map[string]suburbState)
+	var errs []string
+	for _, suburb := range e.suburbs {
+		if stage < 1 {
+			states[suburb.ID] = suburbState{known: true}
+			continue
+		}
+
+		schedules, err := client.Schedule(ctx, suburb.ID, stage)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("suburb %s: %v", suburb.ID, err))
+			states[suburb.ID] = suburbState{}
+			continue
+		}
+
+		schedule := schedules[stage]
+		state := suburbState{known: true}
+		_, state.powerOff = schedule.ActiveAt(now)
+		if next, ok := schedule.Next(now); ok {
+			state.nextOutage = next.Start
+		}
+		states[suburb.ID] = state
+	}
+
+	e.mu.Lock()
+	e.stage, e.stageKnown = stage, true
+	for id, state := range states {
+		e.suburbStates[id] = state
+	}
+	if len(errs) == 0 || ctx.Err() == nil {
+		e.refreshOK = len(errs) == 0
+	}
+	e.mu.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("refreshing schedules: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	e.write(&buf)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// write formats all metrics into buf.
+func (e *Exporter) write(buf *bytes.Buffer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stageKnown {
+		writeHeader(buf, "eskom_loadshedding_stage", "gauge", "Current loadshedding stage, 0 when there is no loadshedding.")
+		fmt.Fprintf(buf, "eskom_loadshedding_stage %d\n", e.stage)
+	}
+
+	writeHeader(buf, "eskom_exporter_last_refresh_success", "gauge", "Whether the last refresh of the metrics succeeded.")
+	fmt.Fprintf(buf, "eskom_exporter_last_refresh_success %d\n", boolValue(e.refreshOK))
+
+	endpoints := make([]string, 0, len(e.durations))
+	for endpoint := range e.durations {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	writeHeader(buf, "eskom_api_request_duration_seconds", "histogram", "Duration of requests to the Eskom API.")
+	for _, endpoint := range endpoints {
+		h := e.durations[endpoint]
+		label := labels("endpoint", endpoint)
+		for i, bound := range e.buckets {
+			fmt.Fprintf(buf, "eskom_api_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", label, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(buf, "eskom_api_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(buf, "eskom_api_request_duration_seconds_sum{%s} %s\n", label, formatFloat(h.sum))
+		fmt.Fprintf(buf, "eskom_api_request_duration_seconds_count{%s} %d\n", label, h.count)
+	}
+
+	writeHeader(buf, "eskom_api_errors_total", "counter", "Failed requests to the Eskom API.")
+	for _, endpoint := range endpoints {
+		fmt.Fprintf(buf, "eskom_api_errors_total{%s} %d\n", labels("endpoint", endpoint), e.errors[endpoint])
+	}
+
+	writeHeader(buf, "eskom_suburb_power_off", "gauge", "Whether the suburb is currently loadshedding.")
+	for _, suburb := range e.suburbs {
+		if state := e.suburbStates[suburb.ID]; state.known {
+			fmt.Fprintf(buf, "eskom_suburb_power_off{%s} %d\n", suburbLabels(suburb), boolValue(state.powerOff))
+		}
+	}
+
+	writeHeader(buf, "eskom_suburb_next_outage_timestamp", "gauge", "Unix time of the start of the next outage of the suburb.")
+	for _, suburb := range e.suburbs {
+		if state := e.suburbStates[suburb.ID]; state.known && !state.nextOutage.IsZero() {
+			fmt.Fprintf(buf, "eskom_suburb_next_outage_timestamp{%s} %d\n", suburbLabels(suburb), state.nextOutage.Unix())
+		}
+	}
+}
+
+func writeHeader(buf *bytes.Buffer, name, metricType, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func suburbLabels(suburb Suburb) string {
+	return labels("suburb_id", suburb.ID, "suburb", suburb.Name)
+}
+
+// labels formats the given name and value pairs as a label set without braces.
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+labelEscaper.Replace(pairs[i+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,178 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+)
+
+func TestObserveRequest(t *testing.T) {
+	e := New(WithBuckets([]float64{1, 0.1}))
+	e.ObserveRequest("/GetStatus", 50*time.Millisecond, nil)
+	e.ObserveRequest("/GetStatus", 500*time.Millisecond, errors.New("failed"))
+	e.ObserveRequest("/GetStatus", 2*time.Second, nil)
+
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	expected := `# HELP eskom_exporter_last_refresh_success Whether the last refresh of the metrics succeeded.
+# TYPE eskom_exporter_last_refresh_success gauge
+eskom_exporter_last_refresh_success 0
+# HELP eskom_api_request_duration_seconds Duration of requests to the Eskom API.
+# TYPE eskom_api_request_duration_seconds histogram
+eskom_api_request_duration_seconds_bucket{endpoint="/GetStatus",le="0.1"} 1
+eskom_api_request_duration_seconds_bucket{endpoint="/GetStatus",le="1"} 2
+eskom_api_request_duration_seconds_bucket{endpoint="/GetStatus",le="+Inf"} 3
+eskom_api_request_duration_seconds_sum{endpoint="/GetStatus"} 2.55
+eskom_api_request_duration_seconds_count{endpoint="/GetStatus"} 3
+# HELP eskom_api_errors_total Failed requests to the Eskom API.
+# TYPE eskom_api_errors_total counter
+eskom_api_errors_total{endpoint="/GetStatus"} 1
+# HELP eskom_suburb_power_off Whether the suburb is currently loadshedding.
+# TYPE eskom_suburb_power_off gauge
+# HELP eskom_suburb_next_outage_timestamp Unix time of the start of the next outage of the suburb.
+# TYPE eskom_suburb_next_outage_timestamp gauge
+`
+	if body := res.Body.String(); body != expected {
+		t.Errorf("unexpected metrics:\n%s\nexpected:\n%s", body, expected)
+	}
+	if contentType := res.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %s", contentType)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	scheduleData, err := os.ReadFile("../test_data/schedule.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := "3"
+	eskom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/GetStatus":
+			w.Write([]byte(status))
+		case "/GetScheduleM/1/2/_/1":
+			w.Write(scheduleData)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer eskom.Close()
+
+	e := New(WithSuburb("1", `Bryanston "North"`), WithSuburb("2", "Sandton"))
+	client := eskomlol.New(eskomlol.WithBaseURL(eskom.URL), eskomlol.WithRequestObserver(e.ObserveRequest))
+
+	schedules, err := client.Schedule(context.Background(), "1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := schedules[2].Times[3]
+	e.nowFunc = func() time.Time { return item.Start.Add(-time.Minute) }
+
+	if err := e.Refresh(context.Background(), client); err == nil {
+		t.Error("expected an error for the schedule of suburb 2")
+	}
+
+	body := metrics(e)
+
+	for _, line := range []string{
+		"eskom_loadshedding_stage 2\n",
+		"eskom_exporter_last_refresh_success 0\n",
+		`eskom_suburb_power_off{suburb_id="1",suburb="Bryanston \"North\""} 0` + "\n",
+		`eskom_suburb_next_outage_timestamp{suburb_id="1",suburb="Bryanston \"North\""} ` + strconv.FormatInt(item.Start.Unix(), 10) + "\n",
+		`eskom_api_errors_total{endpoint="/GetScheduleM"} 1` + "\n",
+		`eskom_api_request_duration_seconds_count{endpoint="/GetStatus"} 1` + "\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected metrics to contain %q:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, `suburb_id="2"`) {
+		t.Errorf("expected no metrics for suburb 2:\n%s", body)
+	}
+
+	// During an outage the suburb is off, and without loadshedding it is always on.
+	e.nowFunc = func() time.Time { return item.Start.Add(time.Minute) }
+	e.Refresh(context.Background(), client)
+	if !strings.Contains(metrics(e), `eskom_suburb_power_off{suburb_id="1",suburb="Bryanston \"North\""} 1`) {
+		t.Errorf("expected suburb 1 to be off:\n%s", metrics(e))
+	}
+
+	// A suburb whose schedule can no longer be retrieved loses its metrics.
+	status = "4"
+	if err := e.Refresh(context.Background(), client); err == nil {
+		t.Error("expected an error for the schedules of stage 3")
+	}
+	if body := metrics(e); strings.Contains(body, `suburb_id="1"`) {
+		t.Errorf("expected no metrics for suburb 1 after a failed refresh:\n%s", body)
+	}
+
+	status = "1"
+	if err := e.Refresh(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+	body = metrics(e)
+	if !strings.Contains(body, "eskom_loadshedding_stage 0\n") || !strings.Contains(body, `eskom_suburb_power_off{suburb_id="2",suburb="Sandton"} 0`) {
+		t.Errorf("unexpected metrics without loadshedding:\n%s", body)
+	}
+	if strings.Contains(body, "eskom_suburb_next_outage_timestamp{") {
+		t.Errorf("expected no next outage without loadshedding:\n%s", body)
+	}
+}
+
+func TestRun(t *testing.T) {
+	eskom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("4"))
+	}))
+	defer eskom.Close()
+
+	e := New(WithRefreshInterval(time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := e.Run(ctx, eskomlol.New(eskomlol.WithBaseURL(eskom.URL))); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if !strings.Contains(metrics(e), "eskom_loadshedding_stage 3\n") {
+		t.Errorf("unexpected metrics:\n%s", metrics(e))
+	}
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+	if !strings.Contains(string(body), "eskom_exporter_last_refresh_success 1\n") {
+		t.Errorf("unexpected metrics:\n%s", body)
+	}
+}
+
+func TestWithRefreshInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if e := New(WithRefreshInterval(interval)); e.interval != defaultRefreshInterval {
+			t.Errorf("expected the default interval for %s, got %s", interval, e.interval)
+		}
+	}
+	if e := New(WithRefreshInterval(time.Second)); e.interval != time.Second {
+		t.Errorf("expected an interval of 1s, got %s", e.interval)
+	}
+}
+
+func metrics(e *Exporter) string {
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return res.Body.String()
+}
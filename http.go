@@ -1,63 +1,191 @@
 package eskomlol
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 )
 
 const (
-	baseURL   string = "http://loadshedding.eskom.co.za/LoadShedding"
-	userAgent string = "Mozilla/5.0 (X11; Linux x86_64; rv:69.0) Gecko/20100101 Firefox/69.0"
+	defaultBaseURL   string = "http://loadshedding.eskom.co.za/LoadShedding"
+	defaultUserAgent string = "Mozilla/5.0 (X11; Linux x86_64; rv:69.0) Gecko/20100101 Firefox/69.0"
 )
 
+// HttpClient is the interface used by the Client to perform HTTP requests.
+//
+// *http.Client satisfies this interface and is used by default.
 type HttpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-func defaultRequest(ctx context.Context, endpoint string, body io.Reader) (*http.Request, error) {
-	requestURL := baseURL + endpoint
+func defaultRequest(ctx context.Context, c *Client, endpoint string, body io.Reader) (*http.Request, error) {
+	requestURL := c.baseURL + endpoint
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, body)
 	if err != nil {
 		return req, err
 	}
 
-	req.Header.Add("User-Agent", userAgent)
+	req.Header.Add("User-Agent", c.userAgent)
 
 	return req, nil
 }
 
-func doRequest(ctx context.Context, client HttpClient, endpoint string, body io.Reader) ([]byte, error) {
-	req, err := defaultRequest(ctx, endpoint, body)
+// doRequest performs a request to the given endpoint, serving responses from the
+// Cache of the Client when available.
+//
+// The response is passed to decode, when not nil, and only cached once it has been decoded
+// successfully. This prevents a maintenance page or truncated response, which Eskom sometimes
+// serves with a 200 status, from being served from the cache until it expires.
+func doRequest(ctx context.Context, c *Client, endpoint string, body io.Reader, decode func([]byte) error) ([]byte, error) {
+	if decode == nil {
+		decode = func([]byte) error { return nil }
+	}
+
+	if c.cache == nil || body != nil {
+		return doRequestWithRetry(ctx, c, endpoint, body, decode)
+	}
+
+	key := c.baseURL + endpoint
+	ttl := cacheTTL(c, endpoint)
+	now := c.nowFunc()
+
+	entry, cached := c.cache.Get(key)
+	if cached && ttl > 0 && now.Sub(entry.StoredAt) < ttl && decode(entry.Data) == nil {
+		return entry.Data, nil
+	}
+
+	data, err := doRequestWithRetry(ctx, c, endpoint, body, decode)
+	if err == nil {
+		if ttl > 0 {
+			// A failure to cache the response should not fail the request itself.
+			_ = c.cache.Set(key, CacheEntry{Data: data, StoredAt: now})
+		}
+		return data, nil
+	}
+
+	if cached && c.staleOnError && ctx.Err() == nil && decode(entry.Data) == nil {
+		return entry.Data, nil
+	}
+	return nil, err
+}
+
+// doRequestWithRetry performs a request to the given endpoint, retrying according to the
+// RetryPolicy of the Client, and decodes the response. Responses that fail to decode are not
+// retried.
+func doRequestWithRetry(ctx context.Context, c *Client, endpoint string, body io.Reader, decode func([]byte) error) ([]byte, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = ioutil.ReadAll(body); err != nil {
+			return nil, err
+		}
+	}
+
+	var data []byte
+	var decodeErr error
+	err := c.retryPolicy.Do(ctx, func() (err error) {
+		data, decodeErr, err = doAttempt(ctx, c, endpoint, payload, decode)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := client.Do(req)
+	return data, decodeErr
+}
+
+// doAttempt performs a single request to the given endpoint and decodes the response. The
+// decode error is returned separately from the request error, but both are observed as a
+// failed request.
+func doAttempt(ctx context.Context, c *Client, endpoint string, payload []byte, decode func([]byte) error) (data []byte, decodeErr, err error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := defaultRequest(ctx, c, endpoint, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if l := rateLimiterFor(c, endpoint); l != nil {
+		if err := l.Wait(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	start := time.Now()
+	data, err = doHTTP(c, req, endpoint)
+	if err == nil {
+		decodeErr = decode(data)
+	}
+	if c.requestObserver != nil {
+		observed := err
+		if observed == nil {
+			observed = decodeErr
+		}
+		c.requestObserver(endpointName(endpoint), time.Since(start), observed)
+	}
+
+	return data, decodeErr, err
+}
+
+// doHTTP sends the request and reads the response body, returning an *APIError for
+// unsuccessful status codes.
+func doHTTP(c *Client, req *http.Request, endpoint string) ([]byte, error) {
+	res, err := getClient(c).Do(req)
 	if err != nil {
 		return nil, err
 	}
 
 	defer res.Body.Close()
-	data, err := ioutil.ReadAll(res.Body)
 
-	return data, err
-}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		data, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBodyLength+1))
+		apiErr := newAPIError(res.StatusCode, endpoint, data)
+		apiErr.RetryAfter = ParseRetryAfter(res.Header.Get("Retry-After"), c.nowFunc())
+		return nil, apiErr
+	}
 
-func doRequestJSON(ctx context.Context, client HttpClient, endpoint string, body io.Reader, out interface{}) error {
-	data, err := doRequest(ctx, client, endpoint, body)
+	data, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := json.Unmarshal(data, out); err != nil {
-		return fmt.Errorf("%v. response: %s", err, string(data))
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrEmptyResponse
 	}
 
-	return nil
+	return data, nil
+}
+
+func doRequestJSON(ctx context.Context, c *Client, endpoint string, body io.Reader, out interface{}) error {
+	_, err := doRequest(ctx, c, endpoint, body, func(data []byte) error {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%v. response: %s", err, string(data))
+		}
+		return nil
+	})
+
+	return err
+}
+
+// endpointName returns the name of the API endpoint without any path parameters or query,
+// for example "/GetScheduleM" for "/GetScheduleM/1/1/_/1".
+func endpointName(endpoint string) string {
+	if i := strings.Index(endpoint, "?"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	if i := strings.Index(strings.TrimPrefix(endpoint, "/"), "/"); i >= 0 {
+		endpoint = endpoint[:i+1]
+	}
+	return endpoint
 }
 
 func getClient(c *Client) HttpClient {
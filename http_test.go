@@ -3,9 +3,12 @@ package eskomlol
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type mockHTTPClient struct {
@@ -13,7 +16,7 @@ type mockHTTPClient struct {
 }
 
 func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
-	res := http.Response{}
+	res := http.Response{StatusCode: http.StatusOK}
 	buf := bytes.NewBuffer([]byte(m.data))
 	closer := io.NopCloser(buf)
 	res.Body = closer
@@ -23,18 +26,33 @@ func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 
 func TestDefaultRequest(t *testing.T) {
 	ctx := context.Background()
-	req, err := defaultRequest(ctx, "/blah", nil)
+	req, err := defaultRequest(ctx, New(), "/blah", nil)
 	if err != nil {
 		t.Errorf("unexpected error while creating request: %v", err)
 		return
 	}
 
-	if req.URL.String() != baseURL+"/blah" {
-		t.Errorf("expected url to be %s, got %s", baseURL+"/blah", req.URL.String())
+	if req.URL.String() != defaultBaseURL+"/blah" {
+		t.Errorf("expected url to be %s, got %s", defaultBaseURL+"/blah", req.URL.String())
 	}
 
-	if req.Header.Get("User-Agent") != userAgent {
-		t.Errorf("expected User-Agent header to be %s, got %s", userAgent, req.Header.Get("User-Agent"))
+	if req.Header.Get("User-Agent") != defaultUserAgent {
+		t.Errorf("expected User-Agent header to be %s, got %s", defaultUserAgent, req.Header.Get("User-Agent"))
+	}
+
+	c := New(WithBaseURL("http://localhost:8080/eskom/"), WithUserAgent("eskomlol-test"))
+	req, err = defaultRequest(ctx, c, "/blah", nil)
+	if err != nil {
+		t.Errorf("unexpected error while creating request: %v", err)
+		return
+	}
+
+	if req.URL.String() != "http://localhost:8080/eskom/blah" {
+		t.Errorf("expected url to be %s, got %s", "http://localhost:8080/eskom/blah", req.URL.String())
+	}
+
+	if req.Header.Get("User-Agent") != "eskomlol-test" {
+		t.Errorf("expected User-Agent header to be %s, got %s", "eskomlol-test", req.Header.Get("User-Agent"))
 	}
 }
 
@@ -43,7 +61,7 @@ func TestDoRequest(t *testing.T) {
 		data: "boo",
 	}
 
-	data, err := doRequest(context.Background(), &client, "/blah", nil)
+	data, err := doRequest(context.Background(), New(WithHTTPClient(&client)), "/blah", nil, nil)
 	if err != nil {
 		t.Errorf("unexpected error while performing request: %v", err)
 		return
@@ -54,6 +72,119 @@ func TestDoRequest(t *testing.T) {
 	}
 }
 
+func TestDoRequestStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("<html>Down for maintenance</html>"))
+	}))
+	defer server.Close()
+
+	c := New(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	data, err := doRequest(context.Background(), c, "/GetStatus", nil, nil)
+	if err == nil {
+		t.Error("expected the error to not be nil")
+		return
+	}
+	if data != nil {
+		t.Errorf("expected no response data, got %s", string(data))
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("expected error to be an APIError, got %v", err)
+		return
+	}
+	if apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status code to be %d, got %d", http.StatusServiceUnavailable, apiErr.StatusCode)
+	}
+	if apiErr.Body != "<html>Down for maintenance</html>" {
+		t.Errorf("expected body to be the maintenance page, got %s", apiErr.Body)
+	}
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected error to match ErrUnavailable, got %v", err)
+	}
+}
+
+func TestDoRequestObserver(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("1"))
+	}))
+	defer server.Close()
+
+	type observation struct {
+		endpoint string
+		err      error
+	}
+	var observed []observation
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2}),
+		WithCache(NewMemoryCache(10)),
+		WithRequestObserver(func(endpoint string, duration time.Duration, err error) {
+			if duration <= 0 {
+				t.Errorf("expected a positive duration, got %s", duration)
+			}
+			observed = append(observed, observation{endpoint, err})
+		}),
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := doRequest(context.Background(), c, "/GetScheduleM/1/1/_/1", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The retry is observed, while the cached response is not.
+	if len(observed) != 2 {
+		t.Fatalf("expected 2 observed requests, got %d", len(observed))
+	}
+	if observed[0].endpoint != "/GetScheduleM" || !errors.Is(observed[0].err, ErrUnavailable) {
+		t.Errorf("unexpected first observation %+v", observed[0])
+	}
+	if observed[1].endpoint != "/GetScheduleM" || observed[1].err != nil {
+		t.Errorf("unexpected second observation %+v", observed[1])
+	}
+}
+
+func TestDoRequestObserverDecodeError(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("<html>Down for maintenance</html>"))
+	}))
+	defer server.Close()
+
+	var observed []error
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3}),
+		WithRequestObserver(func(endpoint string, duration time.Duration, err error) {
+			observed = append(observed, err)
+		}),
+	)
+
+	_, err := c.Status(context.Background())
+	if err == nil {
+		t.Fatal("expected a decode error")
+	}
+	// The response is not retried, but observed as a failed request.
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+	if len(observed) != 1 || observed[0] == nil || observed[0].Error() != err.Error() {
+		t.Errorf("expected the decode error to be observed, got %v", observed)
+	}
+}
+
 func TestDoRequestJSON(t *testing.T) {
 	client := mockHTTPClient{
 		data: `{"thing": "yes"}`,
@@ -63,7 +194,7 @@ func TestDoRequestJSON(t *testing.T) {
 		Thing string `json:"thing,omitempty"`
 	}{}
 
-	err := doRequestJSON(context.Background(), &client, "/blah", nil, &resItem)
+	err := doRequestJSON(context.Background(), New(WithHTTPClient(&client)), "/blah", nil, &resItem)
 	if err != nil {
 		t.Errorf("unexpected error while performing request: %v", err)
 		return
@@ -84,3 +215,19 @@ func TestGetClient(t *testing.T) {
 		t.Errorf("expected client to be %v, got %v", expectedClient, client)
 	}
 }
+
+func TestEndpointName(t *testing.T) {
+	tests := map[string]string{
+		"/GetStatus":                       "/GetStatus",
+		"/GetMunicipalities/?Id=1":         "/GetMunicipalities",
+		"/GetScheduleM/1/1/_/1":            "/GetScheduleM",
+		"/FindSuburbs?searchText=a/b":      "/FindSuburbs",
+		"/GetSurburbData/?pageSize=100&id": "/GetSurburbData",
+	}
+
+	for endpoint, expected := range tests {
+		if name := endpointName(endpoint); name != expected {
+			t.Errorf("expected endpoint name of %s to be %s, got %s", endpoint, expected, name)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package eskomlol
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	icalTZID       = "Africa/Johannesburg"
+	icalDateFormat = "20060102T150405"
+	icalLineLength = 75
+)
+
+// ICalEncoder writes schedules to an io.Writer as an RFC 5545 iCalendar.
+//
+// Every ScheduleItem is written as a VEVENT in the Africa/Johannesburg time zone. The UID
+// of each event is derived from the suburb, stage and start time, so calendars subscribed to
+// the output update existing events instead of duplicating them.
+type ICalEncoder struct {
+	w io.Writer
+	// SuburbID is used to derive stable UIDs for the events.
+	SuburbID string
+	// SuburbName is added to the summary of the events, e.g. "Loadshedding Stage 4 – Bryanston".
+	SuburbName string
+	// Alarms adds a reminder to every event for each duration before its start, rounded to whole
+	// seconds.
+	Alarms []time.Duration
+
+	nowFunc func() time.Time
+}
+
+// NewICalEncoder creates an ICalEncoder writing to w.
+func NewICalEncoder(w io.Writer) *ICalEncoder {
+	return &ICalEncoder{w: w, nowFunc: time.Now}
+}
+
+// Encode writes the given schedules as a single calendar.
+func (e *ICalEncoder) Encode(schedules ...Schedule) error {
+	var buf bytes.Buffer
+	writeLine := func(line string) {
+		buf.WriteString(foldICalLine(line))
+	}
+
+	dtstamp := e.nowFunc().UTC().Format(icalDateFormat) + "Z"
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//teamjorge//eskomlol//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("METHOD:PUBLISH")
+	writeLine("BEGIN:VTIMEZONE")
+	writeLine("TZID:" + icalTZID)
+	writeLine("BEGIN:STANDARD")
+	writeLine("DTSTART:19700101T000000")
+	writeLine("TZOFFSETFROM:+0200")
+	writeLine("TZOFFSETTO:+0200")
+	writeLine("TZNAME:SAST")
+	writeLine("END:STANDARD")
+	writeLine("END:VTIMEZONE")
+
+	for _, s := range schedules {
+		summary := "Loadshedding " + s.Stage.Name()
+		if e.SuburbName != "" {
+			summary += " – " + e.SuburbName
+		}
+
+		for _, item := range s.Times {
+			if !item.Valid() {
+				continue
+			}
+
+			writeLine("BEGIN:VEVENT")
+			writeLine("UID:" + e.uid(s.Stage, item.Start))
+			writeLine("DTSTAMP:" + dtstamp)
+			writeLine(fmt.Sprintf("DTSTART;TZID=%s:%s", icalTZID, item.Start.In(sast).Format(icalDateFormat)))
+			writeLine(fmt.Sprintf("DTEND;TZID=%s:%s", icalTZID, item.End.In(sast).Format(icalDateFormat)))
+			writeLine("SUMMARY:" + escapeICalText(summary))
+			if len(item.Feeders) > 0 {
+				writeLine("DESCRIPTION:" + escapeICalText("Feeders: "+strings.Join(item.Feeders, ", ")))
+			}
+			writeLine("TRANSP:OPAQUE")
+			for _, alarm := range e.Alarms {
+				writeLine("BEGIN:VALARM")
+				writeLine("ACTION:DISPLAY")
+				writeLine("DESCRIPTION:" + escapeICalText(summary))
+				writeLine("TRIGGER:-" + icalDuration(alarm))
+				writeLine("END:VALARM")
+			}
+			writeLine("END:VEVENT")
+		}
+	}
+
+	writeLine("END:VCALENDAR")
+
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+// EncodeMap writes the schedules of all stages, as returned by Client.Schedule, as a single calendar.
+func (e *ICalEncoder) EncodeMap(schedules map[Stage]Schedule) error {
+	stages := make([]Stage, 0, len(schedules))
+	for stage := range schedules {
+		stages = append(stages, stage)
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i] < stages[j] })
+
+	ordered := make([]Schedule, 0, len(stages))
+	for _, stage := range stages {
+		ordered = append(ordered, schedules[stage])
+	}
+
+	return e.Encode(ordered...)
+}
+
+// uid derives a stable UID for an event from the suburb, stage and start time.
+func (e *ICalEncoder) uid(stage Stage, start time.Time) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s/%d/%s", e.SuburbID, stage, start.UTC().Format(time.RFC3339))))
+	return hex.EncodeToString(sum[:]) + "@eskomlol"
+}
+
+// escapeICalText escapes a TEXT value as described in RFC 5545 section 3.3.11.
+func escapeICalText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+// icalDuration formats a positive duration as described in RFC 5545 section 3.3.6, rounded to
+// whole seconds.
+func icalDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	d = d.Round(time.Second)
+
+	var b strings.Builder
+	b.WriteString("P")
+	if days := d / (24 * time.Hour); days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		d -= days * 24 * time.Hour
+	}
+	if d == 0 {
+		if b.Len() == 1 {
+			return "PT0S"
+		}
+		return b.String()
+	}
+
+	b.WriteString("T")
+	if hours := d / time.Hour; hours > 0 {
+		fmt.Fprintf(&b, "%dH", hours)
+		d -= hours * time.Hour
+	}
+	if minutes := d / time.Minute; minutes > 0 {
+		fmt.Fprintf(&b, "%dM", minutes)
+		d -= minutes * time.Minute
+	}
+	if seconds := d / time.Second; seconds > 0 {
+		fmt.Fprintf(&b, "%dS", seconds)
+	}
+	return b.String()
+}
+
+// foldICalLine terminates a content line with CRLF, folding it so that no line exceeds
+// 75 octets without splitting multi-byte characters.
+func foldICalLine(line string) string {
+	var b strings.Builder
+	limit := icalLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards the limit.
+		limit = icalLineLength - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package eskomlol
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICalEncoderEncode(t *testing.T) {
+	loc := loadLocation("Africa/Johannesburg")
+	var buf bytes.Buffer
+
+	e := NewICalEncoder(&buf)
+	e.SuburbID = "1058852"
+	e.SuburbName = "Bryanston"
+	e.Alarms = []time.Duration{15 * time.Minute}
+	e.nowFunc = func() time.Time { return time.Date(2021, 10, 27, 16, 0, 0, 0, time.UTC) }
+
+	schedule := Schedule{
+		Stage: 4,
+		Times: []ScheduleItem{
+			{
+				Start:   time.Date(2021, 11, 10, 22, 0, 0, 0, loc),
+				End:     time.Date(2021, 11, 11, 0, 30, 0, 0, loc),
+				Feeders: []string{"BRYNORTH; CHIBBA 1", "CHIBBA 2"},
+			},
+			// Invalid items are skipped
+			{Start: time.Date(2021, 11, 12, 22, 0, 0, 0, loc)},
+		},
+	}
+
+	if err := e.Encode(schedule); err != nil {
+		t.Errorf("unexpected error encoding schedule: %v", err)
+		return
+	}
+
+	output := buf.String()
+	expectedLines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"TZID:Africa/Johannesburg",
+		"TZOFFSETTO:+0200",
+		"BEGIN:VEVENT",
+		"UID:" + e.uid(4, time.Date(2021, 11, 10, 20, 0, 0, 0, time.UTC)),
+		"DTSTAMP:20211027T160000Z",
+		"DTSTART;TZID=Africa/Johannesburg:20211110T220000",
+		"DTEND;TZID=Africa/Johannesburg:20211111T003000",
+		"SUMMARY:Loadshedding Stage 4 – Bryanston",
+		`DESCRIPTION:Feeders: BRYNORTH\; CHIBBA 1\, CHIBBA 2`,
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(output, line+"\r\n") {
+			t.Errorf("expected output to contain line %q", line)
+		}
+	}
+
+	if count := strings.Count(output, "BEGIN:VEVENT"); count != 1 {
+		t.Errorf("expected 1 event, got %d", count)
+	}
+}
+
+func TestICalEncoderStableUID(t *testing.T) {
+	start := time.Date(2021, 11, 10, 22, 0, 0, 0, sast)
+	e := &ICalEncoder{SuburbID: "1"}
+
+	uid := e.uid(4, start)
+	if uid != e.uid(4, start.UTC()) {
+		t.Error("expected the UID to not depend on the location of the start time")
+	}
+	if uid == e.uid(5, start) {
+		t.Error("expected the UID to differ between stages")
+	}
+	if uid == e.uid(4, start.Add(time.Hour)) {
+		t.Error("expected the UID to differ between start times")
+	}
+	if uid == (&ICalEncoder{SuburbID: "2"}).uid(4, start) {
+		t.Error("expected the UID to differ between suburbs")
+	}
+}
+
+func TestICalEncoderEncodeMap(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewICalEncoder(&buf)
+
+	start := time.Date(2021, 11, 10, 22, 0, 0, 0, sast)
+	err := e.EncodeMap(map[Stage]Schedule{
+		6: {Stage: 6, Times: []ScheduleItem{{Start: start, End: start.Add(time.Hour)}}},
+		2: {Stage: 2, Times: []ScheduleItem{{Start: start, End: start.Add(time.Hour)}}},
+	})
+	if err != nil {
+		t.Errorf("unexpected error encoding schedules: %v", err)
+		return
+	}
+
+	output := buf.String()
+	stage2 := strings.Index(output, "SUMMARY:Loadshedding Stage 2\r\n")
+	stage6 := strings.Index(output, "SUMMARY:Loadshedding Stage 6\r\n")
+	if stage2 < 0 || stage6 < 0 || stage2 > stage6 {
+		t.Errorf("expected events of stage 2 before stage 6, got:\n%s", output)
+	}
+}
+
+func TestFoldICalLine(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("–", 60)
+	folded := foldICalLine(line)
+
+	for _, part := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(part) > icalLineLength {
+			t.Errorf("expected folded lines to be at most %d octets, got %d", icalLineLength, len(part))
+		}
+	}
+
+	unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", "")
+	if unfolded != line {
+		t.Errorf("expected unfolded line to be %s, got %s", line, unfolded)
+	}
+
+	if short := foldICalLine("VERSION:2.0"); short != "VERSION:2.0\r\n" {
+		t.Errorf("expected short lines to not be folded, got %q", short)
+	}
+}
+
+func TestICalDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                               "PT0S",
+		15 * time.Minute:                "PT15M",
+		90 * time.Minute:                "PT1H30M",
+		24 * time.Hour:                  "P1D",
+		25*time.Hour + time.Second:      "P1DT1H1S",
+		-30 * time.Minute:               "PT30M",
+		400 * time.Millisecond:          "PT0S",
+		1500 * time.Millisecond:         "PT2S",
+		24*time.Hour + time.Millisecond: "P1D",
+	}
+
+	for d, expected := range tests {
+		if value := icalDuration(d); value != expected {
+			t.Errorf("expected duration %v to be %s, got %s", d, expected, value)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package eskomlol
+
+import (
+	"sort"
+	"time"
+)
+
+// LocationSchedules maps a label, such as the name or ID of a suburb, to its Schedule.
+//
+// It is used to combine the schedules of several locations, for example from multiple calls
+// to Client.Schedule for different suburbs.
+type LocationSchedules map[string]Schedule
+
+// LabelledWindow is a Window in which loadshedding affects the listed locations.
+type LabelledWindow struct {
+	Window
+	// Locations are the labels of the affected locations in ascending order.
+	Locations []string
+}
+
+// Union returns the windows in which at least one location has loadshedding.
+//
+// Windows are split wherever the set of affected locations changes, so that each window
+// lists exactly the locations without power for its full duration.
+func (l LocationSchedules) Union() []LabelledWindow {
+	return l.segments(1)
+}
+
+// Intersection returns the windows in which all locations have loadshedding.
+func (l LocationSchedules) Intersection() []LabelledWindow {
+	if len(l) == 0 {
+		return []LabelledWindow{}
+	}
+	return l.segments(len(l))
+}
+
+// segments returns the windows in which at least minLocations locations have loadshedding,
+// combining adjacent windows affecting the same locations.
+func (l LocationSchedules) segments(minLocations int) []LabelledWindow {
+	labels := make([]string, 0, len(l))
+	merged := make(map[string][]ScheduleItem, len(l))
+	points := make([]time.Time, 0)
+	for label, s := range l {
+		labels = append(labels, label)
+		merged[label] = s.Merged()
+		for _, item := range merged[label] {
+			points = append(points, item.Start, item.End)
+		}
+	}
+	sort.Strings(labels)
+	sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j]) })
+
+	res := make([]LabelledWindow, 0)
+	for i := 0; i+1 < len(points); i++ {
+		start, end := points[i], points[i+1]
+		if !end.After(start) {
+			continue
+		}
+
+		active := make([]string, 0)
+		for _, label := range labels {
+			for _, item := range merged[label] {
+				if !start.Before(item.Start) && start.Before(item.End) {
+					active = append(active, label)
+					break
+				}
+			}
+		}
+		if len(active) == 0 || len(active) < minLocations {
+			continue
+		}
+
+		if n := len(res); n > 0 && res[n-1].End.Equal(start) && equalStrings(res[n-1].Locations, active) {
+			res[n-1].End = end
+			continue
+		}
+		res = append(res, LabelledWindow{Window: Window{Start: start, End: end}, Locations: active})
+	}
+
+	return res
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
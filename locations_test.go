@@ -0,0 +1,83 @@
+package eskomlol
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func locationsTestSchedules() LocationSchedules {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+
+	return LocationSchedules{
+		"office": {Stage: 4, Times: []ScheduleItem{
+			{Start: at(8, 0), End: at(10, 30)},
+			{Start: at(16, 0), End: at(18, 30)},
+		}},
+		"datacentre": {Stage: 4, Times: []ScheduleItem{
+			{Start: at(10, 0), End: at(12, 30)},
+			{Start: at(16, 0), End: at(18, 30)},
+		}},
+		"home": {Stage: 4, Times: []ScheduleItem{
+			{Start: at(17, 0), End: at(19, 0)},
+		}},
+	}
+}
+
+func TestLocationSchedulesUnion(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+
+	expected := []LabelledWindow{
+		{Window: Window{Start: at(8, 0), End: at(10, 0)}, Locations: []string{"office"}},
+		{Window: Window{Start: at(10, 0), End: at(10, 30)}, Locations: []string{"datacentre", "office"}},
+		{Window: Window{Start: at(10, 30), End: at(12, 30)}, Locations: []string{"datacentre"}},
+		{Window: Window{Start: at(16, 0), End: at(17, 0)}, Locations: []string{"datacentre", "office"}},
+		{Window: Window{Start: at(17, 0), End: at(18, 30)}, Locations: []string{"datacentre", "home", "office"}},
+		{Window: Window{Start: at(18, 30), End: at(19, 0)}, Locations: []string{"home"}},
+	}
+
+	assertLabelledWindows(t, locationsTestSchedules().Union(), expected)
+}
+
+func TestLocationSchedulesIntersection(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+
+	schedules := locationsTestSchedules()
+	assertLabelledWindows(t, schedules.Intersection(), []LabelledWindow{
+		{Window: Window{Start: at(17, 0), End: at(18, 30)}, Locations: []string{"datacentre", "home", "office"}},
+	})
+
+	delete(schedules, "home")
+	assertLabelledWindows(t, schedules.Intersection(), []LabelledWindow{
+		{Window: Window{Start: at(10, 0), End: at(10, 30)}, Locations: []string{"datacentre", "office"}},
+		{Window: Window{Start: at(16, 0), End: at(18, 30)}, Locations: []string{"datacentre", "office"}},
+	})
+
+	if windows := (LocationSchedules{}).Intersection(); len(windows) != 0 {
+		t.Errorf("expected no windows without locations, got %d", len(windows))
+	}
+}
+
+func assertLabelledWindows(t *testing.T, windows, expected []LabelledWindow) {
+	t.Helper()
+
+	if len(windows) != len(expected) {
+		t.Errorf("expected %d windows, got %d: %v", len(expected), len(windows), windows)
+		return
+	}
+
+	for index, window := range windows {
+		if !window.Start.Equal(expected[index].Start) || !window.End.Equal(expected[index].End) {
+			t.Errorf("expected window %d to be %s - %s, got %s - %s", index, expected[index].Start, expected[index].End, window.Start, window.End)
+		}
+		if strings.Join(window.Locations, ",") != strings.Join(expected[index].Locations, ",") {
+			t.Errorf("expected window %d locations to be %v, got %v", index, expected[index].Locations, window.Locations)
+		}
+	}
+}
@@ -1,24 +1,142 @@
 package eskomlol
 
 import (
+	"strings"
 	"time"
 )
 
 type ClientOpt func(*Client)
 
 // WithTimeout sets the http timeout for the Client to the given duration.
+//
+// The timeout is ignored when a custom HttpClient is provided with WithHTTPClient.
 func WithTimeout(timeout time.Duration) ClientOpt {
 	return func(c *Client) {
 		c.timeout = timeout
 	}
 }
 
-func withHTTPClient(httpClient HttpClient) ClientOpt {
+// WithHTTPClient sets the HttpClient used to perform all requests.
+//
+// This can be used to route requests through a proxy, a mock server or
+// any custom transport.
+func WithHTTPClient(httpClient HttpClient) ClientOpt {
 	return func(c *Client) {
 		c.httpClient = httpClient
 	}
 }
 
+// WithBaseURL overrides the base URL of the Eskom API.
+//
+// Any trailing slashes are removed, since all endpoints are appended with a leading slash.
+func WithBaseURL(baseURL string) ClientOpt {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) ClientOpt {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetryPolicy retries failed requests according to the given RetryPolicy.
+//
+// DefaultRetryPolicy provides sensible defaults for the Eskom API. Requests are not retried by default.
+func WithRetryPolicy(policy RetryPolicy) ClientOpt {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithRateLimiter makes all requests of the Client wait for a permit from the given RateLimiter.
+//
+// The same RateLimiter can be passed to multiple Clients to share the limit between them.
+func WithRateLimiter(limiter *RateLimiter) ClientOpt {
+	return func(c *Client) {
+		c.rateLimiter = limiter
+	}
+}
+
+// WithEndpointRateLimiter overrides the RateLimiter for a single endpoint, such as "/GetScheduleM".
+//
+// Requests to the endpoint will only wait for the given RateLimiter and not the one set with WithRateLimiter.
+func WithEndpointRateLimiter(endpoint string, limiter *RateLimiter) ClientOpt {
+	return func(c *Client) {
+		if c.endpointRateLimiters == nil {
+			c.endpointRateLimiters = make(map[string]*RateLimiter)
+		}
+		c.endpointRateLimiters[endpointName(endpoint)] = limiter
+	}
+}
+
+// WithCache caches responses in the given Cache.
+//
+// Responses are cached per endpoint, ranging from 5 minutes for /GetStatus to a week for
+// /GetMunicipalities and /GetSurburbData. The defaults can be changed with WithCacheTTL.
+func WithCache(cache Cache) ClientOpt {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// WithCacheTTL sets how long responses of an endpoint, such as "/GetStatus", are cached.
+//
+// A TTL of zero disables caching for the endpoint.
+func WithCacheTTL(endpoint string, ttl time.Duration) ClientOpt {
+	return func(c *Client) {
+		if c.cacheTTLs == nil {
+			c.cacheTTLs = make(map[string]time.Duration)
+		}
+		c.cacheTTLs[endpointName(endpoint)] = ttl
+	}
+}
+
+// WithStaleOnError serves the last cached response, regardless of its age, when a request fails.
+//
+// This has no effect unless a Cache is set with WithCache.
+func WithStaleOnError(enabled bool) ClientOpt {
+	return func(c *Client) {
+		c.staleOnError = enabled
+	}
+}
+
+// WithScheduleConcurrency sets the maximum number of stages fetched concurrently by Schedule.
+//
+// The default is 4. A value of 1 fetches stages sequentially.
+func WithScheduleConcurrency(concurrency int) ClientOpt {
+	return func(c *Client) {
+		c.scheduleConcurrency = concurrency
+	}
+}
+
+// WithLocation sets the time zone in which schedule times are parsed.
+//
+// The default is Africa/Johannesburg, or a fixed SAST (+02:00) zone when the time zone
+// database is not available.
+func WithLocation(loc *time.Location) ClientOpt {
+	return func(c *Client) {
+		if loc != nil {
+			c.location = loc
+		}
+	}
+}
+
+// WithRequestObserver sets a function called after every HTTP request to the Eskom API, such as
+// for collecting metrics.
+//
+// The endpoint name excludes path parameters and query (for example "/GetScheduleM"). Every retry is
+// observed separately, while responses served from the cache are not observed at all. A response
+// that cannot be decoded, such as a maintenance page served with a 200 status, is observed with
+// the decode error.
+func WithRequestObserver(fn func(endpoint string, duration time.Duration, err error)) ClientOpt {
+	return func(c *Client) {
+		c.requestObserver = fn
+	}
+}
+
 func withNowFunc(nowFunc func() time.Time) ClientOpt {
 	return func(c *Client) {
 		c.nowFunc = nowFunc
@@ -36,9 +36,34 @@ func TestOpts(t *testing.T) {
 
 	httpClient := fakeOptsHTTPClient{}
 
-	withHTTPClient(&httpClient)(&c)
+	WithHTTPClient(&httpClient)(&c)
 
 	if c.httpClient != &httpClient {
 		t.Errorf("expected httpClient to be %v, got: %v", &httpClient, c.httpClient)
 	}
+
+	WithBaseURL("http://localhost:8080/")(&c)
+
+	if c.baseURL != "http://localhost:8080" {
+		t.Errorf("expected baseURL to be http://localhost:8080, got: %s", c.baseURL)
+	}
+
+	loc := time.FixedZone("TEST", 3*60*60)
+	WithLocation(loc)(&c)
+
+	if c.location != loc {
+		t.Errorf("expected location to be %v, got: %v", loc, c.location)
+	}
+
+	WithLocation(nil)(&c)
+
+	if c.location != loc {
+		t.Errorf("expected a nil location to be ignored, got: %v", c.location)
+	}
+
+	WithUserAgent("eskomlol")(&c)
+
+	if c.userAgent != "eskomlol" {
+		t.Errorf("expected userAgent to be eskomlol, got: %s", c.userAgent)
+	}
 }
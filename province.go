@@ -1,5 +1,12 @@
 package eskomlol
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type Province int
 
 const (
@@ -13,3 +20,115 @@ const (
 	NorthernCape
 	WesternCape
 )
+
+var provinceNames = map[Province]string{
+	EasternCape:  "Eastern Cape",
+	FreeState:    "Free State",
+	Gauteng:      "Gauteng",
+	KwazuluNatal: "KwaZulu-Natal",
+	Limpopo:      "Limpopo",
+	Mpumalanga:   "Mpumalanga",
+	NorthWest:    "North West",
+	NorthernCape: "Northern Cape",
+	WesternCape:  "Western Cape",
+}
+
+// Provinces returns all provinces in the order of their values.
+func Provinces() []Province {
+	return []Province{
+		EasternCape,
+		FreeState,
+		Gauteng,
+		KwazuluNatal,
+		Limpopo,
+		Mpumalanga,
+		NorthWest,
+		NorthernCape,
+		WesternCape,
+	}
+}
+
+// Name returns the name of the province, such as "Eastern Cape".
+func (p Province) Name() string {
+	if name, ok := provinceNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Province(%d)", int(p))
+}
+
+// String implements fmt.Stringer and returns the same value as Name.
+func (p Province) String() string {
+	return p.Name()
+}
+
+// Valid determines if the province is one of the defined provinces.
+func (p Province) Valid() bool {
+	_, ok := provinceNames[p]
+	return ok
+}
+
+// MarshalText implements encoding.TextMarshaler and returns the Name of the province.
+func (p Province) MarshalText() ([]byte, error) {
+	if !p.Valid() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidProvince, int(p))
+	}
+	return []byte(p.Name()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseProvince.
+func (p *Province) UnmarshalText(text []byte) error {
+	province, err := ParseProvince(string(text))
+	if err != nil {
+		return err
+	}
+	*p = province
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler and encodes the province as its Name.
+func (p Province) MarshalJSON() ([]byte, error) {
+	text, err := p.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler and accepts either a number or any value accepted by ParseProvince.
+func (p *Province) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		var number int
+		if err := json.Unmarshal(data, &number); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidProvince, string(data))
+		}
+		value = strconv.Itoa(number)
+	}
+	return p.UnmarshalText([]byte(value))
+}
+
+// ParseProvince parses a province from its name or number.
+//
+// Names are matched regardless of case, spaces, hyphens and underscores, so "KwaZulu-Natal",
+// "kwazulu natal" and "KWAZULU_NATAL" are all accepted.
+func ParseProvince(value string) (Province, error) {
+	if number, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		if p := Province(number); p.Valid() {
+			return p, nil
+		}
+		return 0, fmt.Errorf("%w: %q", ErrInvalidProvince, value)
+	}
+
+	normalised := normaliseProvinceName(value)
+	for p, name := range provinceNames {
+		if normaliseProvinceName(name) == normalised {
+			return p, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrInvalidProvince, value)
+}
+
+func normaliseProvinceName(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
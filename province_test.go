@@ -0,0 +1,82 @@
+package eskomlol
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestProvince(t *testing.T) {
+	if len(Provinces()) != 9 {
+		t.Errorf("expected 9 provinces, got %d", len(Provinces()))
+	}
+	for _, p := range Provinces() {
+		if !p.Valid() {
+			t.Errorf("expected province %d to be valid", int(p))
+		}
+	}
+
+	if name := KwazuluNatal.String(); name != "KwaZulu-Natal" {
+		t.Errorf("expected name to be KwaZulu-Natal, got %s", name)
+	}
+	if name := Province(12).String(); name != "Province(12)" {
+		t.Errorf("expected name to be Province(12), got %s", name)
+	}
+	if Province(0).Valid() {
+		t.Error("expected province 0 to not be valid")
+	}
+}
+
+func TestParseProvince(t *testing.T) {
+	tests := map[string]Province{
+		"Gauteng":       Gauteng,
+		"gauteng":       Gauteng,
+		"3":             Gauteng,
+		"KwaZulu-Natal": KwazuluNatal,
+		"kwazulu natal": KwazuluNatal,
+		"KWAZULU_NATAL": KwazuluNatal,
+		" Western Cape": WesternCape,
+		"northwest":     NorthWest,
+	}
+
+	for value, expected := range tests {
+		p, err := ParseProvince(value)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", value, err)
+			continue
+		}
+		if p != expected {
+			t.Errorf("expected %q to be %s, got %s", value, expected, p)
+		}
+	}
+
+	for _, value := range []string{"", "0", "10", "Atlantis"} {
+		if _, err := ParseProvince(value); !errors.Is(err, ErrInvalidProvince) {
+			t.Errorf("expected %q to fail with ErrInvalidProvince, got %v", value, err)
+		}
+	}
+}
+
+func TestProvinceMarshalling(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Province Province `json:"province"`
+	}{Province: NorthernCape})
+	if err != nil {
+		t.Errorf("unexpected error marshalling province: %v", err)
+		return
+	}
+	if string(data) != `{"province":"Northern Cape"}` {
+		t.Errorf("expected province to be encoded by name, got %s", string(data))
+	}
+
+	var p Province
+	if err := json.Unmarshal([]byte(`"Northern Cape"`), &p); err != nil || p != NorthernCape {
+		t.Errorf("expected Northern Cape to round trip, got %s (%v)", p, err)
+	}
+	if err := json.Unmarshal([]byte(`5`), &p); err != nil || p != Limpopo {
+		t.Errorf("expected numeric JSON to be parsed as Limpopo, got %s (%v)", p, err)
+	}
+	if _, err := json.Marshal(Province(42)); !errors.Is(err, ErrInvalidProvince) {
+		t.Errorf("expected marshalling an invalid province to fail with ErrInvalidProvince, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package eskomlol
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket rate limiter for requests to the Eskom API.
+//
+// A single RateLimiter can be shared by multiple Clients to limit their combined request rate.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	nowFunc func() time.Time
+	stats   RateLimiterStats
+}
+
+// RateLimiterStats contains metrics on the time spent waiting for a RateLimiter.
+type RateLimiterStats struct {
+	// Requests is the number of permits granted.
+	Requests int64
+	// Delayed is the number of permits that required waiting.
+	Delayed int64
+	// TotalWait is the cumulative time spent waiting for permits.
+	TotalWait time.Duration
+	// MaxWait is the longest time spent waiting for a single permit.
+	MaxWait time.Duration
+}
+
+// NewRateLimiter creates a RateLimiter allowing rate requests per second with bursts of up to burst requests.
+//
+// A rate of zero or less disables limiting. A burst below 1 is treated as 1.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		nowFunc: time.Now,
+	}
+}
+
+// Wait blocks until a permit is available or the context is done.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	wait := r.reserve()
+	if wait > 0 {
+		if err := sleepContext(ctx, wait); err != nil {
+			r.cancel()
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.stats.Requests++
+	if wait > 0 {
+		r.stats.Delayed++
+		r.stats.TotalWait += wait
+		if wait > r.stats.MaxWait {
+			r.stats.MaxWait = wait
+		}
+	}
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Stats returns the wait time metrics of the RateLimiter.
+func (r *RateLimiter) Stats() RateLimiterStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// reserve takes a token from the bucket and returns how long to wait before it can be used.
+func (r *RateLimiter) reserve() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.rate <= 0 {
+		return 0
+	}
+
+	now := r.nowFunc()
+	if !r.last.IsZero() {
+		r.tokens += now.Sub(r.last).Seconds() * r.rate
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+	}
+	r.last = now
+
+	r.tokens--
+	if r.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-r.tokens / r.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token that was not used.
+func (r *RateLimiter) cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.rate > 0 {
+		r.tokens++
+	}
+}
+
+// rateLimiterFor returns the RateLimiter to use for the given endpoint, if any.
+func rateLimiterFor(c *Client, endpoint string) *RateLimiter {
+	if l, ok := c.endpointRateLimiters[endpointName(endpoint)]; ok {
+		return l
+	}
+	return c.rateLimiter
+}
@@ -0,0 +1,98 @@
+package eskomlol
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	now := time.Date(2021, 10, 27, 18, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(2, 2)
+	l.nowFunc = func() time.Time { return now }
+
+	expected := []time.Duration{0, 0, 500 * time.Millisecond, time.Second}
+	for index, wait := range expected {
+		if w := l.reserve(); w != wait {
+			t.Errorf("expected reservation %d to wait %v, got %v", index, wait, w)
+		}
+	}
+
+	// Two seconds refill 4 tokens, which pays back the 2 borrowed tokens and fills the bucket.
+	now = now.Add(2 * time.Second)
+	for index, wait := range []time.Duration{0, 0, 500 * time.Millisecond} {
+		if w := l.reserve(); w != wait {
+			t.Errorf("expected reservation %d after refill to wait %v, got %v", index, wait, w)
+		}
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(50, 1)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Errorf("unexpected error waiting for rate limiter: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected 3 permits at 50/s with a burst of 1 to take at least 30ms, took %v", elapsed)
+	}
+
+	stats := l.Stats()
+	if stats.Requests != 3 {
+		t.Errorf("expected 3 requests, got %d", stats.Requests)
+	}
+	if stats.Delayed != 2 {
+		t.Errorf("expected 2 delayed requests, got %d", stats.Delayed)
+	}
+	if stats.TotalWait <= 0 || stats.MaxWait <= 0 || stats.MaxWait > stats.TotalWait {
+		t.Errorf("expected positive wait metrics, got %+v", stats)
+	}
+}
+
+func TestRateLimiterWaitContext(t *testing.T) {
+	l := NewRateLimiter(0.1, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Errorf("unexpected error waiting for rate limiter: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := l.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to match context.DeadlineExceeded, got %v", err)
+	}
+	if l.tokens < 0 || l.tokens > 0.01 {
+		t.Errorf("expected the cancelled reservation to be returned, got %v tokens", l.tokens)
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	l := NewRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		if w := l.reserve(); w != 0 {
+			t.Errorf("expected unlimited rate limiter to not wait, got %v", w)
+		}
+	}
+}
+
+func TestRateLimiterFor(t *testing.T) {
+	global := NewRateLimiter(1, 1)
+	schedule := NewRateLimiter(2, 1)
+	c := New(WithRateLimiter(global), WithEndpointRateLimiter("/GetScheduleM", schedule))
+
+	if l := rateLimiterFor(c, "/GetStatus"); l != global {
+		t.Errorf("expected /GetStatus to use the global rate limiter, got %v", l)
+	}
+	if l := rateLimiterFor(c, "/GetScheduleM/1/1/_/1"); l != schedule {
+		t.Errorf("expected /GetScheduleM to use the endpoint rate limiter, got %v", l)
+	}
+	if l := rateLimiterFor(New(), "/GetStatus"); l != nil {
+		t.Errorf("expected no rate limiter by default, got %v", l)
+	}
+}
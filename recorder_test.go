@@ -0,0 +1,213 @@
+package eskomlol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files in test_data/golden")
+
+func TestRecorder(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if r.URL.Path == "/LoadShedding/GetScheduleM/1/1/_/1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("4"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	recording := New(WithBaseURL(server.URL+"/LoadShedding"), WithHTTPClient(NewRecorder(dir, Record, server.Client())))
+	if stage, err := recording.Status(ctx); err != nil || stage != 3 {
+		t.Fatalf("expected stage 3, got %d (%v)", stage, err)
+	}
+	if _, err := recording.Schedule(ctx, "1", 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 fixtures, got %v", files)
+	}
+
+	// The fixtures are replayed for a Client with a different base URL, without sending requests.
+	replaying := New(WithBaseURL("http://eskom.invalid/LoadShedding/"), WithHTTPClient(NewRecorder(dir, Replay, nil)))
+	if stage, err := replaying.Status(ctx); err != nil || stage != 3 {
+		t.Errorf("expected the replayed stage 3, got %d (%v)", stage, err)
+	}
+	if _, err := replaying.Schedule(ctx, "1", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the replayed ErrNotFound, got %v", err)
+	}
+	if _, err := replaying.Municipalities(ctx, Gauteng); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("expected ErrFixtureNotFound, got %v", err)
+	}
+	if n := atomic.LoadInt64(&requests); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+
+	// Missing fixtures are recorded once.
+	both := New(WithBaseURL(server.URL+"/LoadShedding"), WithHTTPClient(NewRecorder(dir, ReplayOrRecord, server.Client())))
+	for i := 0; i < 2; i++ {
+		if _, err := both.Status(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := both.Suburbs(ctx, "1", "", 1); err == nil {
+			t.Error("expected an error decoding a recorded status as suburbs")
+		}
+	}
+	if n := atomic.LoadInt64(&requests); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+// TestGoldenFixtures replays every fixture in test_data/fixtures through the Client and compares
+// the result to its golden file in test_data/golden. Fixtures captured from the live API with a Recorder are covered
+// automatically, after creating their golden files with go test -run TestGoldenFixtures -update.
+func TestGoldenFixtures(t *testing.T) {
+	files, err := filepath.Glob("./test_data/fixtures/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no fixtures found")
+	}
+
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f fixture
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := replayFixture(f)
+			if err != nil {
+				t.Fatalf("decoding %s: %v", f.Request.URL, err)
+			}
+			got, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("test_data", "golden", filepath.Base(file))
+			if *update {
+				if err := ioutil.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, create it with -update", err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("decoded fixture does not match %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// replayFixture replays the fixture through the Client method requesting its endpoint, so that
+// the golden files cover the decoding of the Client itself.
+func replayFixture(f fixture) (interface{}, error) {
+	u, err := url.Parse(f.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	// Schedules are decoded relative to the time they were recorded at, which determines the
+	// year of each date.
+	c := New(
+		WithHTTPClient(NewRecorder(filepath.Join("test_data", "fixtures"), Replay, nil)),
+		WithLocation(sast),
+		withNowFunc(func() time.Time { return f.RecordedAt }),
+	)
+	ctx := context.Background()
+
+	for i, part := range parts {
+		switch part {
+		case "GetStatus":
+			return c.Status(ctx)
+		case "GetMunicipalities":
+			province, err := strconv.Atoi(query.Get("Id"))
+			if err != nil {
+				return nil, err
+			}
+			return c.Municipalities(ctx, Province(province))
+		case "GetSurburbData":
+			page, err := strconv.Atoi(query.Get("pageNum"))
+			if err != nil {
+				return nil, err
+			}
+			return c.Suburbs(ctx, query.Get("id"), query.Get("searchTerm"), page)
+		case "FindSuburbs":
+			maxResults, err := strconv.Atoi(query.Get("maxResults"))
+			if err != nil {
+				return nil, err
+			}
+			return c.SearchSuburbs(ctx, query.Get("searchText"), &maxResults)
+		case "GetScheduleM":
+			if len(parts) < i+3 {
+				return nil, fmt.Errorf("unexpected schedule path %s", u.Path)
+			}
+			stage, err := strconv.Atoi(parts[i+2])
+			if err != nil {
+				return nil, err
+			}
+			schedules, err := c.Schedule(ctx, parts[i+1], Stage(stage))
+			return schedules[Stage(stage)], err
+		}
+	}
+
+	return nil, fmt.Errorf("unknown endpoint %s", u.Path)
+}
+
+func TestFixtureName(t *testing.T) {
+	a, _ := http.NewRequest(http.MethodGet, "http://a.invalid/LoadShedding/GetScheduleM/1/2/_/1", nil)
+	b, _ := http.NewRequest(http.MethodGet, "http://b.invalid/LoadShedding/GetScheduleM/1/2/_/1", nil)
+	c, _ := http.NewRequest(http.MethodGet, "http://b.invalid/LoadShedding/GetScheduleM/1/3/_/1", nil)
+	d, _ := http.NewRequest(http.MethodGet, "http://a.invalid/GetScheduleM/1/2/_/1", nil)
+
+	if fixtureName(a, nil) != fixtureName(b, nil) {
+		t.Error("expected the host to be ignored")
+	}
+	if fixtureName(b, nil) == fixtureName(c, nil) {
+		t.Error("expected different paths to have different names")
+	}
+	if fixtureName(a, nil) == fixtureName(d, nil) {
+		t.Error("expected the base path to be part of the name")
+	}
+	if fixtureName(b, nil) == fixtureName(b, []byte("body")) {
+		t.Error("expected different bodies to have different names")
+	}
+	if !strings.HasPrefix(fixtureName(a, nil), "LoadShedding_GetScheduleM_1_2_1-") {
+		t.Errorf("unexpected name %s", fixtureName(a, nil))
+	}
+}
@@ -0,0 +1,205 @@
+package eskomlol
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures how failed requests to the Eskom API are retried.
+//
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made for a request, including the first.
+	// Values below 1 are treated as 1.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles with every subsequent retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. A Retry-After value exceeding MaxDelay stops
+	// any further retries. No cap is applied when MaxDelay is zero.
+	MaxDelay time.Duration
+	// Jitter is the fraction (0 to 1) of each delay that is randomised to spread out retries.
+	Jitter float64
+	// Retryable determines if a request should be retried after the given error.
+	// DefaultRetryable is used when nil.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy suitable for the Eskom API.
+//
+// Requests are attempted up to 4 times with delays starting at 500 milliseconds and
+// capped at 10 seconds.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Jitter:      0.2,
+		Retryable:   DefaultRetryable,
+	}
+}
+
+// DefaultRetryable reports whether err is a transient failure worth retrying.
+//
+// Transport errors (including timeouts of the http.Client), empty responses, server errors,
+// rate limiting and request timeouts are retried. Cancellation and other API errors are not.
+//
+// Timeouts of the http.Client match context.DeadlineExceeded, so an expired deadline of the
+// caller is detected from its context by RetryPolicy.Do rather than from the error.
+func DefaultRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode >= 500
+	}
+
+	return true
+}
+
+// RetryAfterError is implemented by errors carrying a delay requested by the server, such as
+// with a Retry-After header. A RetryPolicy waits for this delay instead of its own backoff.
+type RetryAfterError interface {
+	error
+	RetryAfterDelay() time.Duration
+}
+
+// Do calls fn until it succeeds, the attempts of the policy are exhausted or the error is not
+// retryable, and returns the last error.
+//
+// Attempts are spaced with exponential backoff and jitter, unless the error is a RetryAfterError.
+// No further attempts are made once the context is done, in which case the context error is
+// returned.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	attempts := p.attempts()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= attempts || !p.retryable(err) {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		delay, ok := p.delay(attempt, err)
+		if !ok {
+			return err
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable == nil {
+		return DefaultRetryable(err)
+	}
+	return p.Retryable(err)
+}
+
+// delay calculates how long to wait after the given failed attempt.
+//
+// false is returned when the server requested a delay exceeding MaxDelay.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	var retryAfterErr RetryAfterError
+	if errors.As(err, &retryAfterErr) {
+		if retryAfter := retryAfterErr.RetryAfterDelay(); retryAfter > 0 {
+			if p.MaxDelay > 0 && retryAfter > p.MaxDelay {
+				return 0, false
+			}
+			return retryAfter, true
+		}
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 && delay > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= time.Duration(jitter * randFloat64() * float64(delay))
+	}
+
+	return delay, true
+}
+
+var (
+	jitterRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterRandMu sync.Mutex
+)
+
+func randFloat64() float64 {
+	jitterRandMu.Lock()
+	defer jitterRandMu.Unlock()
+	return jitterRand.Float64()
+}
+
+// sleepContext waits for the given duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which is either a number
+// of seconds or an HTTP date. Zero is returned for missing, invalid or past values.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+
+	if d := date.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
@@ -0,0 +1,272 @@
+package eskomlol
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 1, expected: 100 * time.Millisecond},
+		{attempt: 2, expected: 200 * time.Millisecond},
+		{attempt: 3, expected: 400 * time.Millisecond},
+		{attempt: 4, expected: 800 * time.Millisecond},
+		{attempt: 5, expected: time.Second},
+		{attempt: 50, expected: time.Second},
+	}
+
+	for _, test := range tests {
+		delay, ok := policy.delay(test.attempt, errors.New("boom"))
+		if !ok {
+			t.Errorf("expected attempt %d to be retried", test.attempt)
+		}
+		if delay != test.expected {
+			t.Errorf("expected delay of attempt %d to be %v, got %v", test.attempt, test.expected, delay)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay, _ := policy.delay(2, errors.New("boom"))
+		if delay < 100*time.Millisecond || delay > 200*time.Millisecond {
+			t.Errorf("expected jittered delay to be between 100ms and 200ms, got %v", delay)
+		}
+	}
+}
+
+func TestRetryPolicyDelayRetryAfter(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+	delay, ok := policy.delay(1, &APIError{StatusCode: 429, RetryAfter: 3 * time.Second})
+	if !ok {
+		t.Error("expected a Retry-After below MaxDelay to be retried")
+	}
+	if delay != 3*time.Second {
+		t.Errorf("expected delay to be 3s, got %v", delay)
+	}
+
+	_, ok = policy.delay(1, &APIError{StatusCode: 429, RetryAfter: time.Minute})
+	if ok {
+		t.Error("expected a Retry-After above MaxDelay to not be retried")
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	calls := 0
+	err := policy.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return &APIError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success after 3 calls, got %d calls and %v", calls, err)
+	}
+
+	calls = 0
+	err = policy.Do(context.Background(), func() error {
+		calls++
+		return &APIError{StatusCode: http.StatusBadRequest}
+	})
+	if calls != 1 || err == nil {
+		t.Errorf("expected a single call for a client error, got %d calls and %v", calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policy.BaseDelay = time.Hour
+	err = policy.Do(ctx, func() error { return errors.New("boom") })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled while waiting, got %v", err)
+	}
+}
+
+func TestDefaultRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{err: errors.New("connection reset"), expected: true},
+		{err: ErrEmptyResponse, expected: true},
+		{err: &APIError{StatusCode: 500}, expected: true},
+		{err: &APIError{StatusCode: 503}, expected: true},
+		{err: &APIError{StatusCode: 429}, expected: true},
+		{err: &APIError{StatusCode: 408}, expected: true},
+		{err: &APIError{StatusCode: 404}, expected: false},
+		{err: &APIError{StatusCode: 400}, expected: false},
+		{err: context.Canceled, expected: false},
+		{err: context.DeadlineExceeded, expected: true},
+	}
+
+	for _, test := range tests {
+		if DefaultRetryable(test.err) != test.expected {
+			t.Errorf("expected DefaultRetryable(%v) to be %v", test.err, test.expected)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 10, 27, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{value: "", expected: 0},
+		{value: "5", expected: 5 * time.Second},
+		{value: "-5", expected: 0},
+		{value: "Wed, 27 Oct 2021 18:00:30 GMT", expected: 30 * time.Second},
+		{value: "Wed, 27 Oct 2021 17:00:00 GMT", expected: 0},
+		{value: "soon", expected: 0},
+	}
+
+	for _, test := range tests {
+		if d := ParseRetryAfter(test.value, now); d != test.expected {
+			t.Errorf("expected Retry-After %q to be %v, got %v", test.value, test.expected, d)
+		}
+	}
+}
+
+func TestDoRequestRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			// Empty body
+		default:
+			w.Write([]byte("2"))
+		}
+	}))
+	defer server.Close()
+
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	)
+
+	stage, err := c.Status(context.Background())
+	if err != nil {
+		t.Errorf("did not expect an error when calling Status, got: %v", err)
+	}
+	if stage != 1 {
+		t.Errorf("expected stage value to be 1, got %d", stage)
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("expected 3 attempts, got %d", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestDoRequestRetryExhausted(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}),
+	)
+
+	_, err := c.Status(context.Background())
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected error to match ErrRateLimited, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("expected 2 attempts, got %d", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestDoRequestRetryNotRetryable(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond}),
+	)
+
+	_, err := c.Status(context.Background())
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected error to match ErrNotFound, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("expected 1 attempt, got %d", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestDoRequestRetryClientTimeout(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	httpClient := server.Client()
+	httpClient.Timeout = 20 * time.Millisecond
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(httpClient),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	)
+
+	if _, err := c.Status(context.Background()); err == nil {
+		t.Error("expected the timeout to fail the request")
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("expected timeouts of the http.Client to be retried 3 times, got %d attempts", n)
+	}
+}
+
+func TestDoRequestRetryContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	c := New(
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Minute}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Status(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to match context.DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("expected the retry to stop once the context was done")
+	}
+}
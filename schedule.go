@@ -1,8 +1,10 @@
 package eskomlol
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -12,29 +14,183 @@ import (
 // Schedule represents a loadshedding schedule for specific stage.
 type Schedule struct {
 	Stage
-	Times []ScheduleItem
+	// Feeder is the feeder supplying the suburb, as shown on the schedule page.
+	Feeder string
+	Times  []ScheduleItem
 }
 
 // ScheduleItem represents a single instance of loadshedding.
 type ScheduleItem struct {
 	Start time.Time
 	End   time.Time
+	// Feeders are the feeders affected by this instance of loadshedding.
+	Feeders []string
+}
+
+// Valid determines if the ScheduleItem has both a Start and End, with End after Start.
+func (s ScheduleItem) Valid() bool {
+	return !s.Start.IsZero() && !s.End.IsZero() && s.End.After(s.Start)
+}
+
+// Duration returns the length of the loadshedding instance.
+//
+// Zero is returned for an invalid ScheduleItem.
+func (s ScheduleItem) Duration() time.Duration {
+	if !s.Valid() {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// scheduleJSON is the JSON representation of a Schedule.
+type scheduleJSON struct {
+	Stage  Stage          `json:"stage"`
+	Feeder string         `json:"feeder,omitempty"`
+	Times  []ScheduleItem `json:"times"`
+}
+
+// scheduleItemJSON is the JSON representation of a ScheduleItem.
+type scheduleItemJSON struct {
+	Start   string   `json:"start"`
+	End     string   `json:"end"`
+	Feeders []string `json:"feeders,omitempty"`
+}
+
+// String implements fmt.Stringer and lists the stage and all times of the Schedule.
+func (s Schedule) String() string {
+	times := make([]string, 0, len(s.Times))
+	for _, item := range s.Times {
+		times = append(times, item.String())
+	}
+	return fmt.Sprintf("%s: [%s]", s.Stage, strings.Join(times, ", "))
+}
+
+// MarshalJSON implements json.Marshaler. Times are encoded as RFC 3339 timestamps.
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	times := s.Times
+	if times == nil {
+		times = []ScheduleItem{}
+	}
+	return json.Marshal(scheduleJSON{Stage: s.Stage, Feeder: s.Feeder, Times: times})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Schedule) UnmarshalJSON(data []byte) error {
+	var value scheduleJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*s = Schedule{Stage: value.Stage, Feeder: value.Feeder, Times: value.Times}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler using the JSON representation of the Schedule.
+//
+// This prevents the methods of the embedded Stage from encoding only the stage.
+func (s Schedule) MarshalText() ([]byte, error) {
+	return s.MarshalJSON()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using the JSON representation of the Schedule.
+func (s *Schedule) UnmarshalText(text []byte) error {
+	return s.UnmarshalJSON(text)
+}
+
+// String implements fmt.Stringer and returns the start and end as an RFC 3339 interval.
+func (s ScheduleItem) String() string {
+	return s.Start.Format(time.RFC3339) + "/" + s.End.Format(time.RFC3339)
+}
+
+// MarshalText implements encoding.TextMarshaler and encodes the start and end as an
+// RFC 3339 interval, such as "2021-10-29T04:00:00+02:00/2021-10-29T06:30:00+02:00".
+//
+// Feeders are not included in the text representation.
+func (s ScheduleItem) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for the format of MarshalText.
+func (s *ScheduleItem) UnmarshalText(text []byte) error {
+	parts := strings.Split(string(text), "/")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid schedule item %q: expected start/end", string(text))
+	}
+	start, err := time.Parse(time.RFC3339, parts[0])
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(time.RFC3339, parts[1])
+	if err != nil {
+		return err
+	}
+	*s = ScheduleItem{Start: start, End: end}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The start and end are encoded as RFC 3339 timestamps.
+func (s ScheduleItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(scheduleItemJSON{
+		Start:   s.Start.Format(time.RFC3339),
+		End:     s.End.Format(time.RFC3339),
+		Feeders: s.Feeders,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *ScheduleItem) UnmarshalJSON(data []byte) error {
+	var value scheduleItemJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	start, err := time.Parse(time.RFC3339, value.Start)
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(time.RFC3339, value.End)
+	if err != nil {
+		return err
+	}
+	*s = ScheduleItem{Start: start, End: end, Feeders: value.Feeders}
+	return nil
 }
 
 // rawItem is used to parse the raw values from the Eskom page.
 type rawItem struct {
 	date, time string
+	feeders    []string
 }
 
+// feederSlot is a single entry of the JSON passed to showFeeder on the Eskom page.
+type feederSlot struct {
+	Time   string `json:"Time"`
+	Feeder string `json:"Feeder"`
+}
+
+var (
+	scheduleDayRegexp = regexp.MustCompile(`class="scheduleDay"`)
+	showFeederRegexp  = regexp.MustCompile(`showFeeder\('(.*?)'\)`)
+)
+
 // parseScheduleHTML iterates the schedule HTML page and parses any date and time combinations.
 //
+// Every time slot of a day is returned, which includes the text of each anchor as well as any
+// additional times found in the JSON passed to showFeeder.
+//
 // Any non-ErrElementNotFound will be added to the returned error object
 func parseScheduleHTML(data []byte) ([]rawItem, error) {
 	errs := make([]string, 0)
 	res := make([]rawItem, 0)
 	document := soup.HTMLParse(string(data))
 	days := document.FindAll("div", "class", "scheduleDay")
-	for _, day := range days {
+
+	// The showFeeder JSON is not valid inside the onclick attribute and gets mangled by the
+	// HTML parser, so it is extracted from the raw section of each day instead.
+	dayChunks := splitScheduleDays(data)
+	if len(dayChunks) != len(days) {
+		dayChunks = nil
+	}
+
+	for i, day := range days {
 		dateDiv := day.Find("div", "class", "dayMonth")
 		if dateDiv.Error != nil {
 			if dateDiv.Error.(soup.Error).Type != soup.ErrElementNotFound {
@@ -43,15 +199,37 @@ func parseScheduleHTML(data []byte) ([]rawItem, error) {
 			continue
 		}
 		date := strings.TrimSpace(dateDiv.Text())
-		timesA := day.Find("a")
-		if timesA.Error != nil {
-			if timesA.Error.(soup.Error).Type != soup.ErrElementNotFound {
-				errs = append(errs, timesA.Error.Error())
+
+		times := make([]string, 0)
+		feeders := make(map[string][]string)
+		for _, timesA := range day.FindAll("a") {
+			if time := strings.TrimSpace(timesA.Text()); time != "" && !containsString(times, time) {
+				times = append(times, time)
 			}
-			continue
 		}
-		time := strings.TrimSpace(timesA.Text())
-		res = append(res, rawItem{date: date, time: time})
+
+		if dayChunks != nil {
+			slots, err := parseFeederSlots(dayChunks[i])
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", date, err))
+			}
+			for _, slot := range slots {
+				time := strings.TrimSpace(slot.Time)
+				if time == "" {
+					continue
+				}
+				if !containsString(times, time) {
+					times = append(times, time)
+				}
+				if feeder := strings.TrimSpace(slot.Feeder); feeder != "" && !containsString(feeders[time], feeder) {
+					feeders[time] = append(feeders[time], feeder)
+				}
+			}
+		}
+
+		for _, time := range times {
+			res = append(res, rawItem{date: date, time: time, feeders: feeders[time]})
+		}
 	}
 
 	var err error
@@ -62,43 +240,134 @@ func parseScheduleHTML(data []byte) ([]rawItem, error) {
 	return res, err
 }
 
+// parseFeederHTML returns the feeder from the area information of the schedule page.
+//
+// An empty string is returned when the page does not contain a feeder.
+func parseFeederHTML(data []byte) string {
+	document := soup.HTMLParse(string(data))
+	for _, item := range document.FindAll("div", "class", "areaInfoItem") {
+		label := item.Find("span", "class", "areaInfoLabel")
+		if label.Error != nil {
+			continue
+		}
+		labelText := strings.TrimSpace(label.Text())
+		if strings.TrimSpace(strings.TrimSuffix(labelText, ":")) != "Feeder" {
+			continue
+		}
+		return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(item.FullText()), labelText))
+	}
+	return ""
+}
+
+// splitScheduleDays splits the raw schedule page into the sections of each scheduleDay.
+func splitScheduleDays(data []byte) [][]byte {
+	indexes := scheduleDayRegexp.FindAllIndex(data, -1)
+	chunks := make([][]byte, 0, len(indexes))
+	for i, index := range indexes {
+		end := len(data)
+		if i+1 < len(indexes) {
+			end = indexes[i+1][0]
+		}
+		chunks = append(chunks, data[index[0]:end])
+	}
+	return chunks
+}
+
+// parseFeederSlots parses the JSON of every showFeeder call in the given section of the page.
+func parseFeederSlots(data []byte) ([]feederSlot, error) {
+	res := make([]feederSlot, 0)
+	for _, match := range showFeederRegexp.FindAllSubmatch(data, -1) {
+		var slots []feederSlot
+		if err := json.Unmarshal(match[1], &slots); err != nil {
+			return res, fmt.Errorf("invalid feeder data: %v", err)
+		}
+		res = append(res, slots...)
+	}
+	return res, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sast is used when the Africa/Johannesburg time zone is not available on the system.
+// South Africa has not observed daylight saving time since 1944, so a fixed zone is exact.
+var sast = time.FixedZone("SAST", 2*60*60)
+
+// loadLocation loads the time zone with the given name, falling back to SAST if it is not available.
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return sast
+	}
+	return loc
+}
+
 // makeScheduleItems parses the given rawItems into ScheduleItems.
 //
-// All times returned will be in SAST.
-// Since the raw data has no value for the year, logic is applied to ensure
-// that the year increments correctly for cases such as:
+// All times are parsed in the given location, which should be SAST.
+// Since the raw data has no value for the year, the year of the first item is chosen
+// to be the one closest to now, and it increments whenever the month decreases.
+// This handles cases such as:
 // A schedule going from 1 December 2021 to 31 January 2022.
+// A schedule going from December straight to February.
+// A schedule starting in December when it is already January.
+//
+// Items ending at or before their start time, such as "22:00 - 00:30", end on the following day.
 //
 // Any errors occurred while parsing the rawItems will be added to the returned
 // error object
-func makeScheduleItems(rawItems []rawItem, now time.Time) ([]ScheduleItem, error) {
+func makeScheduleItems(rawItems []rawItem, now time.Time, loc *time.Location) ([]ScheduleItem, error) {
 	res := make([]ScheduleItem, 0)
 	errs := make([]string, 0)
-	currentYear := now.Year()
-
-	var previousMonth string
+	now = now.In(loc)
 
-	parseFormat := "Mon, 02 Jan 2006 15:04 -0700 MST"
+	var currentYear int
+	var previousMonth time.Month
 
 	for _, rawItem := range rawItems {
-		month := rawItem.date[len(rawItem.date)-3:]
-		if month != "" && month != previousMonth && month == "Jan" {
+		date, err := time.Parse("Mon, 2 Jan", rawItem.date)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		if previousMonth == 0 {
+			currentYear = closestYear(date.Month(), date.Day(), now)
+		} else if date.Month() < previousMonth {
 			currentYear++
 		}
-		previousMonth = month
+		previousMonth = date.Month()
+
 		rawTimeParts := strings.Split(rawItem.time, " - ")
-		lowerTime, upperTime := rawTimeParts[0], rawTimeParts[1]
+		if len(rawTimeParts) != 2 {
+			errs = append(errs, fmt.Sprintf("invalid time range %q for %s", rawItem.time, rawItem.date))
+			continue
+		}
 
-		startTime, err := time.Parse(parseFormat, fmt.Sprintf("%s %d %s +0200 SAST", rawItem.date, currentYear, lowerTime))
+		lowerTime, err := time.Parse("15:04", strings.TrimSpace(rawTimeParts[0]))
 		if err != nil {
 			errs = append(errs, err.Error())
+			continue
 		}
-		endTime, err := time.Parse(parseFormat, fmt.Sprintf("%s %d %s +0200 SAST", rawItem.date, currentYear, upperTime))
+		upperTime, err := time.Parse("15:04", strings.TrimSpace(rawTimeParts[1]))
 		if err != nil {
 			errs = append(errs, err.Error())
+			continue
+		}
+
+		startTime := time.Date(currentYear, date.Month(), date.Day(), lowerTime.Hour(), lowerTime.Minute(), 0, 0, loc)
+		endTime := time.Date(currentYear, date.Month(), date.Day(), upperTime.Hour(), upperTime.Minute(), 0, 0, loc)
+		if !endTime.After(startTime) {
+			endTime = endTime.AddDate(0, 0, 1)
 		}
 
-		res = append(res, ScheduleItem{Start: startTime, End: endTime})
+		res = append(res, ScheduleItem{Start: startTime, End: endTime, Feeders: rawItem.feeders})
 	}
 
 	var err error
@@ -109,20 +378,47 @@ func makeScheduleItems(rawItems []rawItem, now time.Time) ([]ScheduleItem, error
 	return res, err
 }
 
+// closestYear returns the year in which the given month and day is closest to now.
+func closestYear(month time.Month, day int, now time.Time) int {
+	year := now.Year()
+	closest := time.Duration(-1)
+	for _, candidate := range []int{now.Year() - 1, now.Year(), now.Year() + 1} {
+		diff := time.Date(candidate, month, day, 0, 0, 0, 0, now.Location()).Sub(now)
+		if diff < 0 {
+			diff = -diff
+		}
+		if closest < 0 || diff < closest {
+			year, closest = candidate, diff
+		}
+	}
+	return year
+}
+
 // scheduleFromHTML parses a Schedule from the given HTML page contents.
-func scheduleFromHTML(data []byte, stage Stage, now time.Time) (Schedule, error) {
+func scheduleFromHTML(data []byte, stage Stage, now time.Time, loc *time.Location) (Schedule, error) {
 	res, err := parseScheduleHTML(data)
 	if err != nil {
-		return Schedule{}, err
+		return Schedule{}, fmt.Errorf("%w: %v", ErrParse, err)
 	}
 
-	scheduleItems, err := makeScheduleItems(res, now)
+	scheduleItems, err := makeScheduleItems(res, now, loc)
 	if err != nil {
-		return Schedule{}, err
+		return Schedule{}, fmt.Errorf("%w: %v", ErrParse, err)
+	}
+
+	// Fall back to the feeder of the page for items without feeder information of their own.
+	feeder := parseFeederHTML(data)
+	if feeder != "" {
+		for i := range scheduleItems {
+			if len(scheduleItems[i].Feeders) == 0 {
+				scheduleItems[i].Feeders = []string{feeder}
+			}
+		}
 	}
 
 	return Schedule{
-		Stage: stage,
-		Times: scheduleItems,
+		Stage:  stage,
+		Feeder: feeder,
+		Times:  scheduleItems,
 	}, nil
 }
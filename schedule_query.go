@@ -0,0 +1,174 @@
+package eskomlol
+
+import (
+	"sort"
+	"time"
+)
+
+// Window is a period of time, such as a period in which power is available.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the Window.
+func (w Window) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// Merged returns the valid Times of the Schedule in chronological order, with overlapping
+// and adjacent items combined into a single item.
+//
+// The Feeders of combined items are merged as well.
+func (s Schedule) Merged() []ScheduleItem {
+	return mergeItems(s.Times)
+}
+
+// ActiveAt returns the loadshedding instance in progress at t, if any.
+func (s Schedule) ActiveAt(t time.Time) (ScheduleItem, bool) {
+	for _, item := range s.Merged() {
+		if !t.Before(item.Start) && t.Before(item.End) {
+			return item, true
+		}
+	}
+	return ScheduleItem{}, false
+}
+
+// Next returns the first loadshedding instance starting at or after the given time, if any.
+//
+// An instance already in progress at after is not returned. Use ActiveAt for that instead.
+func (s Schedule) Next(after time.Time) (ScheduleItem, bool) {
+	for _, item := range s.Merged() {
+		if !item.Start.Before(after) {
+			return item, true
+		}
+	}
+	return ScheduleItem{}, false
+}
+
+// Between returns all loadshedding instances that overlap with the range from - to.
+//
+// The instances are returned as is and are not clipped to the range.
+func (s Schedule) Between(from, to time.Time) []ScheduleItem {
+	res := make([]ScheduleItem, 0)
+	for _, item := range s.Merged() {
+		if item.Start.Before(to) && item.End.After(from) {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// TotalDowntime returns the total duration of loadshedding within the range from - to.
+func (s Schedule) TotalDowntime(from, to time.Time) time.Duration {
+	var total time.Duration
+	for _, item := range s.Between(from, to) {
+		start, end := item.Start, item.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		total += end.Sub(start)
+	}
+	return total
+}
+
+// NextPowerOn returns when power is restored after t.
+//
+// t is returned as is when there is no loadshedding in progress at t.
+func (s Schedule) NextPowerOn(t time.Time) time.Time {
+	if item, ok := s.ActiveAt(t); ok {
+		return item.End
+	}
+	return t
+}
+
+// PowerWindows returns the windows within from - to in which power is available according
+// to the Schedule.
+//
+// Windows shorter than minLength are omitted.
+func (s Schedule) PowerWindows(from, to time.Time, minLength time.Duration) []Window {
+	return PowerWindows(from, to, minLength, s)
+}
+
+// PowerWindows returns the windows within from - to in which power is available according to
+// all of the given schedules, such as the schedules of several suburbs or stages.
+//
+// Power is considered unavailable whenever any of the schedules has loadshedding.
+// Windows shorter than minLength are omitted.
+func PowerWindows(from, to time.Time, minLength time.Duration, schedules ...Schedule) []Window {
+	items := make([]ScheduleItem, 0)
+	for _, s := range schedules {
+		items = append(items, s.Times...)
+	}
+
+	res := make([]Window, 0)
+	add := func(start, end time.Time) {
+		if end.After(start) && end.Sub(start) >= minLength {
+			res = append(res, Window{Start: start, End: end})
+		}
+	}
+
+	cursor := from
+	for _, item := range mergeItems(items) {
+		if !item.Start.Before(to) {
+			break
+		}
+		if !item.End.After(cursor) {
+			continue
+		}
+		if item.Start.After(cursor) {
+			add(cursor, item.Start)
+		}
+		cursor = item.End
+	}
+	if cursor.Before(to) {
+		add(cursor, to)
+	}
+
+	return res
+}
+
+// mergeItems sorts the valid items and combines those that overlap or are adjacent.
+func mergeItems(items []ScheduleItem) []ScheduleItem {
+	sorted := make([]ScheduleItem, 0, len(items))
+	for _, item := range items {
+		if item.Valid() {
+			sorted = append(sorted, item)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	res := make([]ScheduleItem, 0, len(sorted))
+	for _, item := range sorted {
+		if len(res) > 0 && !item.Start.After(res[len(res)-1].End) {
+			last := &res[len(res)-1]
+			if item.End.After(last.End) {
+				last.End = item.End
+			}
+			last.Feeders = mergeFeeders(last.Feeders, item.Feeders)
+			continue
+		}
+		item.Feeders = mergeFeeders(nil, item.Feeders)
+		res = append(res, item)
+	}
+	return res
+}
+
+// mergeFeeders returns a new slice with the unique feeders of a and b.
+func mergeFeeders(a, b []string) []string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(a)+len(b))
+	for _, feeders := range [][]string{a, b} {
+		for _, feeder := range feeders {
+			if !containsString(res, feeder) {
+				res = append(res, feeder)
+			}
+		}
+	}
+	return res
+}
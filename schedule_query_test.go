@@ -0,0 +1,253 @@
+package eskomlol
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// queryTestSchedule returns a Schedule with overlapping, adjacent and unsorted items on 1 November 2021.
+func queryTestSchedule() Schedule {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+
+	return Schedule{
+		Stage: 4,
+		Times: []ScheduleItem{
+			{Start: at(18, 0), End: at(20, 30), Feeders: []string{"A"}},
+			{Start: at(2, 0), End: at(4, 30), Feeders: []string{"A"}},
+			// Overlaps with 02:00 - 04:30
+			{Start: at(4, 0), End: at(6, 30), Feeders: []string{"B"}},
+			{Start: at(10, 0), End: at(12, 30), Feeders: []string{"A"}},
+			// Adjacent to 10:00 - 12:30
+			{Start: at(12, 30), End: at(14, 0), Feeders: []string{"A"}},
+			// Invalid items are ignored
+			{Start: at(16, 0), End: at(15, 0)},
+		},
+	}
+}
+
+func TestScheduleMerged(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+
+	expected := []ScheduleItem{
+		{Start: at(2, 0), End: at(6, 30), Feeders: []string{"A", "B"}},
+		{Start: at(10, 0), End: at(14, 0), Feeders: []string{"A"}},
+		{Start: at(18, 0), End: at(20, 30), Feeders: []string{"A"}},
+	}
+
+	merged := queryTestSchedule().Merged()
+	if len(merged) != len(expected) {
+		t.Errorf("expected %d merged items, got %d", len(expected), len(merged))
+		return
+	}
+
+	for index, item := range merged {
+		if !item.Start.Equal(expected[index].Start) || !item.End.Equal(expected[index].End) {
+			t.Errorf("expected item %d to be %s - %s, got %s - %s", index, expected[index].Start, expected[index].End, item.Start, item.End)
+		}
+		if strings.Join(item.Feeders, ",") != strings.Join(expected[index].Feeders, ",") {
+			t.Errorf("expected item %d feeders to be %v, got %v", index, expected[index].Feeders, item.Feeders)
+		}
+	}
+}
+
+func TestScheduleActiveAt(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+	s := queryTestSchedule()
+
+	tests := []struct {
+		t      time.Time
+		active bool
+		end    time.Time
+	}{
+		{t: at(1, 59), active: false},
+		{t: at(2, 0), active: true, end: at(6, 30)},
+		{t: at(4, 15), active: true, end: at(6, 30)},
+		{t: at(6, 30), active: false},
+		{t: at(12, 30), active: true, end: at(14, 0)},
+		{t: at(15, 30), active: false},
+	}
+
+	for _, test := range tests {
+		item, ok := s.ActiveAt(test.t)
+		if ok != test.active {
+			t.Errorf("expected ActiveAt(%s) to be %v", test.t, test.active)
+			continue
+		}
+		if ok && !item.End.Equal(test.end) {
+			t.Errorf("expected ActiveAt(%s) to end at %s, got %s", test.t, test.end, item.End)
+		}
+
+		expectedPowerOn := test.t
+		if test.active {
+			expectedPowerOn = test.end
+		}
+		if powerOn := s.NextPowerOn(test.t); !powerOn.Equal(expectedPowerOn) {
+			t.Errorf("expected NextPowerOn(%s) to be %s, got %s", test.t, expectedPowerOn, powerOn)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+	s := queryTestSchedule()
+
+	tests := []struct {
+		after time.Time
+		found bool
+		start time.Time
+	}{
+		{after: at(0, 0), found: true, start: at(2, 0)},
+		{after: at(2, 0), found: true, start: at(2, 0)},
+		{after: at(4, 15), found: true, start: at(10, 0)},
+		{after: at(14, 0), found: true, start: at(18, 0)},
+		{after: at(18, 1), found: false},
+	}
+
+	for _, test := range tests {
+		item, ok := s.Next(test.after)
+		if ok != test.found {
+			t.Errorf("expected Next(%s) found to be %v", test.after, test.found)
+			continue
+		}
+		if ok && !item.Start.Equal(test.start) {
+			t.Errorf("expected Next(%s) to start at %s, got %s", test.after, test.start, item.Start)
+		}
+	}
+}
+
+func TestScheduleBetween(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+	s := queryTestSchedule()
+
+	tests := []struct {
+		from, to time.Time
+		count    int
+		downtime time.Duration
+	}{
+		{from: at(0, 0), to: at(23, 59), count: 3, downtime: 4*time.Hour + 30*time.Minute + 4*time.Hour + 2*time.Hour + 30*time.Minute},
+		{from: at(5, 0), to: at(11, 0), count: 2, downtime: 90*time.Minute + time.Hour},
+		{from: at(6, 30), to: at(10, 0), count: 0, downtime: 0},
+		{from: at(19, 0), to: at(23, 0), count: 1, downtime: 90 * time.Minute},
+	}
+
+	for _, test := range tests {
+		items := s.Between(test.from, test.to)
+		if len(items) != test.count {
+			t.Errorf("expected Between(%s, %s) to return %d items, got %d", test.from, test.to, test.count, len(items))
+		}
+		if downtime := s.TotalDowntime(test.from, test.to); downtime != test.downtime {
+			t.Errorf("expected TotalDowntime(%s, %s) to be %v, got %v", test.from, test.to, test.downtime, downtime)
+		}
+	}
+}
+
+func TestSchedulePowerWindows(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+	s := queryTestSchedule()
+
+	tests := []struct {
+		name      string
+		from, to  time.Time
+		minLength time.Duration
+		expected  []Window
+	}{
+		{
+			name: "full day",
+			from: at(0, 0), to: at(23, 0),
+			expected: []Window{
+				{Start: at(0, 0), End: at(2, 0)},
+				{Start: at(6, 30), End: at(10, 0)},
+				{Start: at(14, 0), End: at(18, 0)},
+				{Start: at(20, 30), End: at(23, 0)},
+			},
+		},
+		{
+			name: "minimum length",
+			from: at(0, 0), to: at(23, 0), minLength: 3 * time.Hour,
+			expected: []Window{
+				{Start: at(6, 30), End: at(10, 0)},
+				{Start: at(14, 0), End: at(18, 0)},
+			},
+		},
+		{
+			name: "starting during loadshedding",
+			from: at(3, 0), to: at(12, 0),
+			expected: []Window{
+				{Start: at(6, 30), End: at(10, 0)},
+			},
+		},
+		{
+			name:     "no loadshedding in range",
+			from:     at(15, 0),
+			to:       at(16, 0),
+			expected: []Window{{Start: at(15, 0), End: at(16, 0)}},
+		},
+		{
+			name:     "only loadshedding in range",
+			from:     at(10, 30),
+			to:       at(13, 0),
+			expected: []Window{},
+		},
+	}
+
+	for _, test := range tests {
+		windows := s.PowerWindows(test.from, test.to, test.minLength)
+		if len(windows) != len(test.expected) {
+			t.Errorf("%s: expected %d windows, got %d", test.name, len(test.expected), len(windows))
+			continue
+		}
+		for index, window := range windows {
+			if !window.Start.Equal(test.expected[index].Start) || !window.End.Equal(test.expected[index].End) {
+				t.Errorf("%s: expected window %d to be %s - %s, got %s - %s", test.name, index,
+					test.expected[index].Start, test.expected[index].End, window.Start, window.End)
+			}
+		}
+	}
+}
+
+func TestPowerWindowsMultipleSchedules(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 1, hour, min, 0, 0, sast)
+	}
+	other := Schedule{
+		Stage: 4,
+		Times: []ScheduleItem{
+			{Start: at(8, 0), End: at(9, 0)},
+			{Start: at(16, 0), End: at(19, 0)},
+		},
+	}
+
+	windows := PowerWindows(at(0, 0), at(23, 0), 90*time.Minute, queryTestSchedule(), other)
+	expected := []Window{
+		{Start: at(0, 0), End: at(2, 0)},
+		{Start: at(6, 30), End: at(8, 0)},
+		{Start: at(14, 0), End: at(16, 0)},
+		{Start: at(20, 30), End: at(23, 0)},
+	}
+
+	if len(windows) != len(expected) {
+		t.Errorf("expected %d windows, got %d", len(expected), len(windows))
+		return
+	}
+	for index, window := range windows {
+		if !window.Start.Equal(expected[index].Start) || !window.End.Equal(expected[index].End) {
+			t.Errorf("expected window %d to be %s - %s, got %s - %s", index, expected[index].Start, expected[index].End, window.Start, window.End)
+		}
+		if window.Duration() < 90*time.Minute {
+			t.Errorf("expected window %d to be at least 90 minutes, got %v", index, window.Duration())
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package eskomlol
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -68,7 +70,7 @@ func TestMakeScheduleItemsNormal(t *testing.T) {
 		return
 	}
 	now := time.Date(2021, 10, 27, 18, 00, 00, 0, loc)
-	scheduleItems, err := makeScheduleItems(testItems, now)
+	scheduleItems, err := makeScheduleItems(testItems, now, loc)
 
 	if err != nil {
 		t.Errorf("unexpected error making schedule items: %v", err)
@@ -86,7 +88,7 @@ func TestMakeScheduleItemsNormal(t *testing.T) {
 		{Start: time.Date(2021, 11, 07, 00, 0, 0, 0, loc), End: time.Date(2021, 11, 07, 02, 30, 00, 0, loc)},
 		{Start: time.Date(2021, 11, 8, 8, 0, 0, 0, loc), End: time.Date(2021, 11, 8, 10, 30, 00, 0, loc)},
 		{Start: time.Date(2021, 11, 9, 14, 0, 0, 0, loc), End: time.Date(2021, 11, 9, 16, 30, 00, 0, loc)},
-		{Start: time.Date(2021, 11, 10, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 10, 00, 30, 00, 0, loc)},
+		{Start: time.Date(2021, 11, 10, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 11, 00, 30, 00, 0, loc)},
 		{Start: time.Date(2021, 11, 12, 06, 0, 0, 0, loc), End: time.Date(2021, 11, 12, 8, 30, 00, 0, loc)},
 		{Start: time.Date(2021, 11, 13, 12, 0, 0, 0, loc), End: time.Date(2021, 11, 13, 14, 30, 00, 0, loc)},
 		{Start: time.Date(2021, 11, 14, 20, 0, 0, 0, loc), End: time.Date(2021, 11, 14, 22, 30, 00, 0, loc)},
@@ -143,7 +145,7 @@ func TestMakeScheduleItemsYearRollover(t *testing.T) {
 	}
 
 	now := time.Date(2021, 12, 25, 18, 00, 00, 0, loc)
-	scheduleItems, err := makeScheduleItems(testItems, now)
+	scheduleItems, err := makeScheduleItems(testItems, now, loc)
 	if err != nil {
 		t.Errorf("unexpected error making schedule items: %v", err)
 		return
@@ -160,7 +162,7 @@ func TestMakeScheduleItemsYearRollover(t *testing.T) {
 		{Start: time.Date(2022, 01, 02, 00, 00, 00, 0, loc), End: time.Date(2022, 01, 02, 02, 30, 00, 0, loc)},
 		{Start: time.Date(2022, 01, 03, 8, 00, 00, 0, loc), End: time.Date(2022, 01, 03, 10, 30, 00, 0, loc)},
 		{Start: time.Date(2022, 01, 04, 14, 00, 00, 0, loc), End: time.Date(2022, 01, 04, 16, 30, 00, 0, loc)},
-		{Start: time.Date(2022, 01, 05, 22, 00, 00, 0, loc), End: time.Date(2022, 01, 05, 00, 30, 00, 0, loc)},
+		{Start: time.Date(2022, 01, 05, 22, 00, 00, 0, loc), End: time.Date(2022, 01, 06, 00, 30, 00, 0, loc)},
 		{Start: time.Date(2022, 01, 06, 06, 00, 00, 0, loc), End: time.Date(2022, 01, 06, 8, 30, 00, 0, loc)},
 		{Start: time.Date(2022, 01, 07, 12, 00, 00, 0, loc), End: time.Date(2022, 01, 07, 14, 30, 00, 0, loc)},
 		{Start: time.Date(2022, 01, 8, 20, 00, 00, 0, loc), End: time.Date(2022, 01, 8, 22, 30, 00, 0, loc)},
@@ -203,7 +205,7 @@ func TestScheduleFromHTML(t *testing.T) {
 		return
 	}
 
-	res, err := scheduleFromHTML(testData, 1, now)
+	res, err := scheduleFromHTML(testData, 1, now, loc)
 	if err != nil {
 		t.Errorf("expected err to be nil, got: %v", err)
 	}
@@ -220,7 +222,7 @@ func TestScheduleFromHTML(t *testing.T) {
 			{Start: time.Date(2021, 11, 07, 00, 0, 0, 0, loc), End: time.Date(2021, 11, 07, 02, 30, 00, 0, loc)},
 			{Start: time.Date(2021, 11, 8, 8, 0, 0, 0, loc), End: time.Date(2021, 11, 8, 10, 30, 00, 0, loc)},
 			{Start: time.Date(2021, 11, 9, 14, 0, 0, 0, loc), End: time.Date(2021, 11, 9, 16, 30, 00, 0, loc)},
-			{Start: time.Date(2021, 11, 10, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 10, 00, 30, 00, 0, loc)},
+			{Start: time.Date(2021, 11, 10, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 11, 00, 30, 00, 0, loc)},
 			{Start: time.Date(2021, 11, 12, 06, 0, 0, 0, loc), End: time.Date(2021, 11, 12, 8, 30, 00, 0, loc)},
 			{Start: time.Date(2021, 11, 13, 12, 0, 0, 0, loc), End: time.Date(2021, 11, 13, 14, 30, 00, 0, loc)},
 			{Start: time.Date(2021, 11, 14, 20, 0, 0, 0, loc), End: time.Date(2021, 11, 14, 22, 30, 00, 0, loc)},
@@ -243,3 +245,469 @@ func TestScheduleFromHTML(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeScheduleItemsOvernight(t *testing.T) {
+	loc, err := time.LoadLocation("Africa/Johannesburg")
+	if err != nil {
+		t.Errorf("unexpected error loading tz data: %v", err)
+		return
+	}
+
+	tests := []struct {
+		name     string
+		item     rawItem
+		now      time.Time
+		expected ScheduleItem
+	}{
+		{
+			name:     "same day",
+			item:     rawItem{date: "Mon, 08 Nov", time: "08:00 - 10:30"},
+			now:      time.Date(2021, 11, 1, 0, 0, 0, 0, loc),
+			expected: ScheduleItem{Start: time.Date(2021, 11, 8, 8, 0, 0, 0, loc), End: time.Date(2021, 11, 8, 10, 30, 0, 0, loc)},
+		},
+		{
+			name:     "ending at midnight",
+			item:     rawItem{date: "Mon, 08 Nov", time: "22:00 - 00:00"},
+			now:      time.Date(2021, 11, 1, 0, 0, 0, 0, loc),
+			expected: ScheduleItem{Start: time.Date(2021, 11, 8, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 9, 0, 0, 0, 0, loc)},
+		},
+		{
+			name:     "overnight",
+			item:     rawItem{date: "Wed, 10 Nov", time: "22:00 - 00:30"},
+			now:      time.Date(2021, 11, 1, 0, 0, 0, 0, loc),
+			expected: ScheduleItem{Start: time.Date(2021, 11, 10, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 11, 0, 30, 0, 0, loc)},
+		},
+		{
+			name:     "month boundary",
+			item:     rawItem{date: "Sun, 31 Oct", time: "22:00 - 00:30"},
+			now:      time.Date(2021, 10, 27, 0, 0, 0, 0, loc),
+			expected: ScheduleItem{Start: time.Date(2021, 10, 31, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 1, 0, 30, 0, 0, loc)},
+		},
+		{
+			name:     "leap year month boundary",
+			item:     rawItem{date: "Sat, 29 Feb", time: "23:00 - 01:30"},
+			now:      time.Date(2020, 2, 20, 0, 0, 0, 0, loc),
+			expected: ScheduleItem{Start: time.Date(2020, 2, 29, 23, 0, 0, 0, loc), End: time.Date(2020, 3, 1, 1, 30, 0, 0, loc)},
+		},
+		{
+			name:     "year boundary",
+			item:     rawItem{date: "Fri, 31 Dec", time: "22:00 - 00:30"},
+			now:      time.Date(2021, 12, 25, 0, 0, 0, 0, loc),
+			expected: ScheduleItem{Start: time.Date(2021, 12, 31, 22, 0, 0, 0, loc), End: time.Date(2022, 1, 1, 0, 30, 0, 0, loc)},
+		},
+	}
+
+	for _, test := range tests {
+		scheduleItems, err := makeScheduleItems([]rawItem{test.item}, test.now, loc)
+		if err != nil {
+			t.Errorf("%s: unexpected error making schedule items: %v", test.name, err)
+			continue
+		}
+		if len(scheduleItems) != 1 {
+			t.Errorf("%s: expected 1 schedule item, got %d", test.name, len(scheduleItems))
+			continue
+		}
+		if !scheduleItems[0].Start.Equal(test.expected.Start) {
+			t.Errorf("%s: expected start to be %s, got %s", test.name, test.expected.Start, scheduleItems[0].Start)
+		}
+		if !scheduleItems[0].End.Equal(test.expected.End) {
+			t.Errorf("%s: expected end to be %s, got %s", test.name, test.expected.End, scheduleItems[0].End)
+		}
+		if !scheduleItems[0].Valid() {
+			t.Errorf("%s: expected schedule item to be valid", test.name)
+		}
+	}
+}
+
+func TestScheduleItemDuration(t *testing.T) {
+	start := time.Date(2021, 11, 10, 22, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		item     ScheduleItem
+		valid    bool
+		expected time.Duration
+	}{
+		{name: "normal", item: ScheduleItem{Start: start, End: start.Add(150 * time.Minute)}, valid: true, expected: 150 * time.Minute},
+		{name: "end before start", item: ScheduleItem{Start: start, End: start.Add(-time.Hour)}, valid: false, expected: 0},
+		{name: "empty", item: ScheduleItem{Start: start, End: start}, valid: false, expected: 0},
+		{name: "zero start", item: ScheduleItem{End: start}, valid: false, expected: 0},
+		{name: "zero end", item: ScheduleItem{Start: start}, valid: false, expected: 0},
+	}
+
+	for _, test := range tests {
+		if test.item.Valid() != test.valid {
+			t.Errorf("%s: expected Valid to be %v", test.name, test.valid)
+		}
+		if d := test.item.Duration(); d != test.expected {
+			t.Errorf("%s: expected duration to be %v, got %v", test.name, test.expected, d)
+		}
+	}
+}
+
+func TestParseScheduleHTMLMultipleSlots(t *testing.T) {
+	testData, err := ioutil.ReadFile("./test_data/schedule_stage4.html")
+	if err != nil {
+		t.Errorf("unexpected error reading test file: %v", err)
+		return
+	}
+
+	rawItems, err := parseScheduleHTML(testData)
+	if err != nil {
+		t.Errorf("unexpected error parsing test file: %v", err)
+		return
+	}
+
+	expected := []rawItem{
+		{date: "Fri, 29 Oct", time: "04:00 - 06:30"},
+		{date: "Fri, 29 Oct", time: "12:00 - 14:30"},
+		{date: "Fri, 29 Oct", time: "20:00 - 22:30"},
+		{date: "Sat, 30 Oct", time: "02:00 - 04:30"},
+		{date: "Sat, 30 Oct", time: "10:00 - 12:30"},
+		{date: "Sun, 31 Oct", time: "14:00 - 16:30"},
+		// Only present in the showFeeder JSON
+		{date: "Sun, 31 Oct", time: "22:00 - 00:30"},
+		{date: "Mon, 01 Nov", time: "08:00 - 10:30"},
+		{date: "Mon, 01 Nov", time: "16:00 - 18:30"},
+	}
+
+	if len(rawItems) != len(expected) {
+		t.Errorf("expected %d raw items, got %d", len(expected), len(rawItems))
+		return
+	}
+
+	for index, rawItem := range rawItems {
+		if expected[index].date != rawItem.date {
+			t.Errorf("expected item %d to date to be %s, got %s", index, expected[index].date, rawItem.date)
+		}
+		if expected[index].time != rawItem.time {
+			t.Errorf("expected item %d to time to be %s, got %s", index, expected[index].time, rawItem.time)
+		}
+	}
+}
+
+func TestParseFeederSlotsInvalid(t *testing.T) {
+	_, err := parseFeederSlots([]byte(`<a onclick="showFeeder('[{"Time":')">04:00 - 06:30</a>`))
+	if err == nil {
+		t.Error("expected an error for invalid feeder data")
+	}
+}
+
+func TestScheduleFromHTMLMultipleSlots(t *testing.T) {
+	loc, err := time.LoadLocation("Africa/Johannesburg")
+	if err != nil {
+		t.Errorf("unexpected error loading tz data: %v", err)
+		return
+	}
+	now := time.Date(2021, 10, 27, 18, 00, 00, 0, loc)
+
+	testData, err := ioutil.ReadFile("./test_data/schedule_stage4.html")
+	if err != nil {
+		t.Errorf("unexpected error reading test file: %v", err)
+		return
+	}
+
+	res, err := scheduleFromHTML(testData, 4, now, loc)
+	if err != nil {
+		t.Errorf("expected err to be nil, got: %v", err)
+		return
+	}
+
+	expected := []ScheduleItem{
+		{Start: time.Date(2021, 10, 29, 4, 0, 0, 0, loc), End: time.Date(2021, 10, 29, 6, 30, 0, 0, loc)},
+		{Start: time.Date(2021, 10, 29, 12, 0, 0, 0, loc), End: time.Date(2021, 10, 29, 14, 30, 0, 0, loc)},
+		{Start: time.Date(2021, 10, 29, 20, 0, 0, 0, loc), End: time.Date(2021, 10, 29, 22, 30, 0, 0, loc)},
+		{Start: time.Date(2021, 10, 30, 2, 0, 0, 0, loc), End: time.Date(2021, 10, 30, 4, 30, 0, 0, loc)},
+		{Start: time.Date(2021, 10, 30, 10, 0, 0, 0, loc), End: time.Date(2021, 10, 30, 12, 30, 0, 0, loc)},
+		{Start: time.Date(2021, 10, 31, 14, 0, 0, 0, loc), End: time.Date(2021, 10, 31, 16, 30, 0, 0, loc)},
+		{Start: time.Date(2021, 10, 31, 22, 0, 0, 0, loc), End: time.Date(2021, 11, 1, 0, 30, 0, 0, loc)},
+		{Start: time.Date(2021, 11, 1, 8, 0, 0, 0, loc), End: time.Date(2021, 11, 1, 10, 30, 0, 0, loc)},
+		{Start: time.Date(2021, 11, 1, 16, 0, 0, 0, loc), End: time.Date(2021, 11, 1, 18, 30, 0, 0, loc)},
+	}
+
+	if len(res.Times) != len(expected) {
+		t.Errorf("expected %d schedule items, got %d", len(expected), len(res.Times))
+		return
+	}
+
+	for index, scheduleItem := range res.Times {
+		if !expected[index].Start.Equal(scheduleItem.Start) {
+			t.Errorf("expected item %d to start to be %s, got %s", index, expected[index].Start, scheduleItem.Start)
+		}
+		if !expected[index].End.Equal(scheduleItem.End) {
+			t.Errorf("expected item %d to end to be %s, got %s", index, expected[index].End, scheduleItem.End)
+		}
+	}
+}
+
+func TestParseFeederHTML(t *testing.T) {
+	for _, file := range []string{"./test_data/schedule.html", "./test_data/schedule_stage4.html"} {
+		testData, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Errorf("unexpected error reading test file: %v", err)
+			return
+		}
+
+		feeder := parseFeederHTML(testData)
+		if feeder != "BRYNORTH / CHIBBA 1 11kV MV Feeder Underground Cable" {
+			t.Errorf("expected feeder of %s to be BRYNORTH / CHIBBA 1 11kV MV Feeder Underground Cable, got %s", file, feeder)
+		}
+	}
+
+	feeder := parseFeederHTML([]byte(`<div class="areaInfoItem"><span class="areaInfoLabel">Area: </span>Bryanston</div>`))
+	if feeder != "" {
+		t.Errorf("expected no feeder, got %s", feeder)
+	}
+}
+
+func TestScheduleFromHTMLFeeders(t *testing.T) {
+	feeder1 := "BRYNORTH / CHIBBA 1 11kV MV Feeder Underground Cable"
+	feeder2 := "BRYNORTH / CHIBBA 2 11kV MV Feeder Underground Cable"
+	now := time.Date(2021, 10, 27, 18, 00, 00, 0, time.UTC)
+
+	testData, err := ioutil.ReadFile("./test_data/schedule_stage4.html")
+	if err != nil {
+		t.Errorf("unexpected error reading test file: %v", err)
+		return
+	}
+
+	res, err := scheduleFromHTML(testData, 4, now, sast)
+	if err != nil {
+		t.Errorf("expected err to be nil, got: %v", err)
+		return
+	}
+
+	if res.Feeder != feeder1 {
+		t.Errorf("expected schedule feeder to be %s, got %s", feeder1, res.Feeder)
+	}
+
+	expected := [][]string{
+		{feeder1},
+		{feeder1},
+		{feeder1},
+		{feeder1},
+		{feeder1, feeder2},
+		{feeder2},
+		{feeder2},
+		{feeder1},
+		{feeder1},
+	}
+
+	if len(res.Times) != len(expected) {
+		t.Errorf("expected %d schedule items, got %d", len(expected), len(res.Times))
+		return
+	}
+
+	for index, scheduleItem := range res.Times {
+		if strings.Join(scheduleItem.Feeders, ", ") != strings.Join(expected[index], ", ") {
+			t.Errorf("expected item %d feeders to be %v, got %v", index, expected[index], scheduleItem.Feeders)
+		}
+	}
+
+	// Items without showFeeder data fall back to the feeder of the page.
+	res, err = scheduleFromHTML([]byte(`
+		<div class="areaInfoItem"><span class="areaInfoLabel">Feeder: </span>`+feeder2+`</div>
+		<div class="scheduleDay"><div class="dayMonth">Fri, 29 Oct</div><a>04:00 - 06:30</a></div>
+	`), 1, now, sast)
+	if err != nil {
+		t.Errorf("expected err to be nil, got: %v", err)
+		return
+	}
+	if len(res.Times) != 1 || len(res.Times[0].Feeders) != 1 || res.Times[0].Feeders[0] != feeder2 {
+		t.Errorf("expected a single item with feeder %s, got %v", feeder2, res.Times)
+	}
+}
+
+func TestMakeScheduleItemsYearSelection(t *testing.T) {
+	loc, err := time.LoadLocation("Africa/Johannesburg")
+	if err != nil {
+		t.Errorf("unexpected error loading tz data: %v", err)
+		return
+	}
+
+	tests := []struct {
+		name     string
+		items    []rawItem
+		now      time.Time
+		expected []time.Time
+	}{
+		{
+			name: "utc server on new year's eve",
+			items: []rawItem{
+				{date: "Fri, 31 Dec", time: "22:00 - 00:30"},
+				{date: "Sat, 01 Jan", time: "04:00 - 06:30"},
+			},
+			// 00:30 on 1 January 2022 in SAST
+			now:      time.Date(2021, 12, 31, 22, 30, 0, 0, time.UTC),
+			expected: []time.Time{time.Date(2021, 12, 31, 22, 0, 0, 0, loc), time.Date(2022, 1, 1, 4, 0, 0, 0, loc)},
+		},
+		{
+			name: "starting in december during january",
+			items: []rawItem{
+				{date: "Thu, 30 Dec", time: "12:00 - 14:30"},
+				{date: "Mon, 03 Jan", time: "12:00 - 14:30"},
+			},
+			now:      time.Date(2022, 1, 2, 10, 0, 0, 0, loc),
+			expected: []time.Time{time.Date(2021, 12, 30, 12, 0, 0, 0, loc), time.Date(2022, 1, 3, 12, 0, 0, 0, loc)},
+		},
+		{
+			name: "skipping january",
+			items: []rawItem{
+				{date: "Fri, 31 Dec", time: "12:00 - 14:30"},
+				{date: "Tue, 01 Feb", time: "12:00 - 14:30"},
+			},
+			now:      time.Date(2021, 12, 20, 10, 0, 0, 0, loc),
+			expected: []time.Time{time.Date(2021, 12, 31, 12, 0, 0, 0, loc), time.Date(2022, 2, 1, 12, 0, 0, 0, loc)},
+		},
+		{
+			name: "starting in january during december",
+			items: []rawItem{
+				{date: "Sat, 01 Jan", time: "12:00 - 14:30"},
+			},
+			now:      time.Date(2021, 12, 28, 10, 0, 0, 0, loc),
+			expected: []time.Time{time.Date(2022, 1, 1, 12, 0, 0, 0, loc)},
+		},
+		{
+			name: "single digit day",
+			items: []rawItem{
+				{date: "Mon, 1 Nov", time: "12:00 - 14:30"},
+			},
+			now:      time.Date(2021, 10, 27, 10, 0, 0, 0, loc),
+			expected: []time.Time{time.Date(2021, 11, 1, 12, 0, 0, 0, loc)},
+		},
+	}
+
+	for _, test := range tests {
+		scheduleItems, err := makeScheduleItems(test.items, test.now, loc)
+		if err != nil {
+			t.Errorf("%s: unexpected error making schedule items: %v", test.name, err)
+			continue
+		}
+		if len(scheduleItems) != len(test.expected) {
+			t.Errorf("%s: expected %d schedule items, got %d", test.name, len(test.expected), len(scheduleItems))
+			continue
+		}
+		for index, scheduleItem := range scheduleItems {
+			if !scheduleItem.Start.Equal(test.expected[index]) {
+				t.Errorf("%s: expected item %d to start at %s, got %s", test.name, index, test.expected[index], scheduleItem.Start)
+			}
+			if scheduleItem.Start.Location() != loc {
+				t.Errorf("%s: expected item %d to be in %s, got %s", test.name, index, loc, scheduleItem.Start.Location())
+			}
+		}
+	}
+}
+
+func TestMakeScheduleItemsLocation(t *testing.T) {
+	loc := time.FixedZone("TEST", 3*60*60)
+	now := time.Date(2021, 10, 27, 18, 00, 00, 0, loc)
+
+	scheduleItems, err := makeScheduleItems([]rawItem{{date: "Fri, 29 Oct", time: "04:00 - 06:30"}}, now, loc)
+	if err != nil {
+		t.Errorf("unexpected error making schedule items: %v", err)
+		return
+	}
+
+	expected := time.Date(2021, 10, 29, 1, 0, 0, 0, time.UTC)
+	if !scheduleItems[0].Start.Equal(expected) {
+		t.Errorf("expected start to be %s, got %s", expected, scheduleItems[0].Start)
+	}
+}
+
+func TestMakeScheduleItemsInvalid(t *testing.T) {
+	scheduleItems, err := makeScheduleItems([]rawItem{
+		{date: "Fri, 29 Oct", time: "04:00 - 06:30"},
+		{date: "29 October", time: "04:00 - 06:30"},
+		{date: "Sat, 30 Oct", time: "04:00"},
+		{date: "Sun, 31 Oct", time: "04:00 - later"},
+	}, time.Date(2021, 10, 27, 18, 00, 00, 0, sast), sast)
+
+	if err == nil {
+		t.Error("expected an error for the invalid items")
+	}
+	if len(scheduleItems) != 1 {
+		t.Errorf("expected only the valid item to be returned, got %d", len(scheduleItems))
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	if loc := loadLocation("Not/AZone"); loc != sast {
+		t.Errorf("expected unknown time zones to fall back to SAST, got %s", loc)
+	}
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	if _, offset := now.In(sast).Zone(); offset != 2*60*60 {
+		t.Errorf("expected the SAST offset to be 2 hours, got %d seconds", offset)
+	}
+}
+
+func TestScheduleMarshalling(t *testing.T) {
+	loc := loadLocation("Africa/Johannesburg")
+	schedule := Schedule{
+		Stage:  4,
+		Feeder: "BRYNORTH",
+		Times: []ScheduleItem{
+			{Start: time.Date(2021, 10, 29, 4, 0, 0, 0, loc), End: time.Date(2021, 10, 29, 6, 30, 0, 0, loc), Feeders: []string{"BRYNORTH"}},
+		},
+	}
+
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		t.Errorf("unexpected error marshalling schedule: %v", err)
+		return
+	}
+
+	expected := `{"stage":"Stage 4","feeder":"BRYNORTH","times":[{"start":"2021-10-29T04:00:00+02:00","end":"2021-10-29T06:30:00+02:00","feeders":["BRYNORTH"]}]}`
+	if string(data) != expected {
+		t.Errorf("expected JSON to be %s, got %s", expected, string(data))
+	}
+
+	var decoded Schedule
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Errorf("unexpected error unmarshalling schedule: %v", err)
+		return
+	}
+	if decoded.Stage != 4 || decoded.Feeder != "BRYNORTH" || len(decoded.Times) != 1 {
+		t.Errorf("expected schedule to round trip, got %v", decoded)
+		return
+	}
+	if !decoded.Times[0].Start.Equal(schedule.Times[0].Start) || !decoded.Times[0].End.Equal(schedule.Times[0].End) {
+		t.Errorf("expected times to round trip, got %v", decoded.Times[0])
+	}
+
+	text, err := schedule.MarshalText()
+	if err != nil || string(text) != expected {
+		t.Errorf("expected text to match JSON, got %s (%v)", string(text), err)
+	}
+
+	if s := schedule.String(); s != "Stage 4: [2021-10-29T04:00:00+02:00/2021-10-29T06:30:00+02:00]" {
+		t.Errorf("unexpected String value: %s", s)
+	}
+
+	empty, _ := json.Marshal(Schedule{Stage: 1})
+	if string(empty) != `{"stage":"Stage 1","times":[]}` {
+		t.Errorf("expected empty schedule to have an empty list of times, got %s", string(empty))
+	}
+}
+
+func TestScheduleItemText(t *testing.T) {
+	var item ScheduleItem
+	if err := item.UnmarshalText([]byte("2021-10-29T04:00:00+02:00/2021-10-29T06:30:00+02:00")); err != nil {
+		t.Errorf("unexpected error unmarshalling schedule item: %v", err)
+		return
+	}
+	if item.Duration() != 150*time.Minute {
+		t.Errorf("expected duration to be 150 minutes, got %v", item.Duration())
+	}
+
+	text, _ := item.MarshalText()
+	if string(text) != "2021-10-29T04:00:00+02:00/2021-10-29T06:30:00+02:00" {
+		t.Errorf("expected text to round trip, got %s", string(text))
+	}
+
+	for _, value := range []string{"2021-10-29T04:00:00+02:00", "yesterday/today"} {
+		if err := item.UnmarshalText([]byte(value)); err == nil {
+			t.Errorf("expected an error unmarshalling %q", value)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+// Package server exposes the Eskom loadshedding API as a JSON REST API.
+//
+// Requests are served by an eskomlol.Client, so a Client configured with eskomlol.WithCache
+// turns the Server into a caching gateway in front of Eskom. Responses carry an ETag and
+// conditional requests with If-None-Match are answered with 304 Not Modified.
+//
+// Endpoints:
+//
+//	GET /status                                      current loadshedding stage
+//	GET /provinces                                   list of provinces
+//	GET /provinces/{province}/municipalities         municipalities of a province (name or number)
+//	GET /municipalities/{id}/suburbs?search=&page=   suburbs of a municipality
+//	GET /suburbs/search?q=&max=                      search all suburbs
+//	GET /suburbs/{id}/schedule?stage=1,2             schedule of a suburb (default is the current stage)
+//	GET /healthz                                     liveness check
+//	GET /readyz                                      readiness check, requires Eskom to be reachable
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+)
+
+const defaultReadyTimeout = 5 * time.Second
+
+// Status is the response of /status.
+type Status struct {
+	Stage        eskomlol.Stage `json:"stage"`
+	Level        int            `json:"level"`
+	Loadshedding bool           `json:"loadshedding"`
+}
+
+// Province is an element of the /provinces response.
+type Province struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Municipality is an element of the /provinces/{province}/municipalities response.
+type Municipality struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Suburb is a suburb of a municipality.
+type Suburb struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Total int    `json:"total"`
+}
+
+// Suburbs is the response of /municipalities/{id}/suburbs.
+type Suburbs struct {
+	Page    int      `json:"page"`
+	Total   int      `json:"total"`
+	Results []Suburb `json:"results"`
+}
+
+// SearchResult is an element of the /suburbs/search response.
+type SearchResult struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Municipality string `json:"municipality"`
+	Province     string `json:"province"`
+	Total        int    `json:"total"`
+}
+
+// Schedules is the response of /suburbs/{id}/schedule.
+//
+// Errors contains the stages that could not be retrieved, keyed by stage name.
+type Schedules struct {
+	SuburbID  string              `json:"suburb_id"`
+	Schedules []eskomlol.Schedule `json:"schedules"`
+	Errors    map[string]string   `json:"errors,omitempty"`
+}
+
+// Error is the body of all unsuccessful responses.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Server is an http.Handler serving the REST API.
+type Server struct {
+	client       *eskomlol.Client
+	readyTimeout time.Duration
+}
+
+type Opt func(*Server)
+
+// WithReadyTimeout sets how long /readyz waits for Eskom. The default is 5 seconds.
+func WithReadyTimeout(timeout time.Duration) Opt {
+	return func(s *Server) {
+		s.readyTimeout = timeout
+	}
+}
+
+// New creates a Server backed by the given Client.
+func New(client *eskomlol.Client, opts ...Opt) *Server {
+	s := &Server{client: client, readyTimeout: defaultReadyTimeout}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// ServeHTTP routes requests to the endpoints of the API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "healthz":
+		writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+	case len(parts) == 1 && parts[0] == "readyz":
+		s.ready(w, r)
+	case len(parts) == 1 && parts[0] == "status":
+		s.status(w, r)
+	case len(parts) == 1 && parts[0] == "provinces":
+		s.provinces(w, r)
+	case len(parts) == 3 && parts[0] == "provinces" && parts[2] == "municipalities":
+		s.municipalities(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "municipalities" && parts[2] == "suburbs":
+		s.suburbs(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "suburbs" && parts[1] == "search":
+		s.search(w, r)
+	case len(parts) == 3 && parts[0] == "suburbs" && parts[2] == "schedule":
+		s.schedule(w, r, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (s *Server) ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.readyTimeout)
+	defer cancel()
+
+	if _, err := s.client.Status(ctx); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	stage, err := s.client.Status(r.Context())
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, Status{Stage: stage, Level: int(stage), Loadshedding: stage > 0})
+}
+
+func (s *Server) provinces(w http.ResponseWriter, r *http.Request) {
+	provinces := make([]Province, 0)
+	for _, p := range eskomlol.Provinces() {
+		provinces = append(provinces, Province{ID: int(p), Name: p.Name()})
+	}
+
+	writeJSON(w, r, http.StatusOK, provinces)
+}
+
+func (s *Server) municipalities(w http.ResponseWriter, r *http.Request, value string) {
+	province, err := eskomlol.ParseProvince(value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := s.client.Municipalities(r.Context(), province)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	municipalities := make([]Municipality, 0, len(result))
+	for _, m := range result {
+		municipalities = append(municipalities, Municipality{ID: m.ID, Name: m.Name})
+	}
+
+	writeJSON(w, r, http.StatusOK, municipalities)
+}
+
+func (s *Server) suburbs(w http.ResponseWriter, r *http.Request, municipalityID string) {
+	query := r.URL.Query()
+	page := 1
+	if value := query.Get("page"); value != "" {
+		var err error
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid page %q", value))
+			return
+		}
+	}
+
+	result, err := s.client.Suburbs(r.Context(), municipalityID, query.Get("search"), page)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	suburbs := Suburbs{Page: page, Total: result.Total, Results: make([]Suburb, 0, len(result.Results))}
+	for _, suburb := range result.Results {
+		suburbs.Results = append(suburbs.Results, Suburb{ID: suburb.ID, Name: suburb.Name, Total: suburb.Total})
+	}
+
+	writeJSON(w, r, http.StatusOK, suburbs)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	term := strings.TrimSpace(query.Get("q"))
+	if term == "" {
+		writeError(w, http.StatusBadRequest, errors.New("the q parameter is required"))
+		return
+	}
+
+	var maxResults *int
+	if value := query.Get("max"); value != "" {
+		max, err := strconv.Atoi(value)
+		if err != nil || max < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid max %q", value))
+			return
+		}
+		maxResults = &max
+	}
+
+	result, err := s.client.SearchSuburbs(r.Context(), term, maxResults)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	suburbs := make([]SearchResult, 0, len(result))
+	for _, suburb := range result {
+		suburbs = append(suburbs, SearchResult{
+			ID:           strconv.Itoa(suburb.ID),
+			Name:         suburb.Name,
+			Municipality: suburb.MunicipalityName,
+			Province:     suburb.ProvinceName,
+			Total:        suburb.Total,
+		})
+	}
+
+	writeJSON(w, r, http.StatusOK, suburbs)
+}
+
+func (s *Server) schedule(w http.ResponseWriter, r *http.Request, suburbID string) {
+	// Suburb IDs are numeric, anything else would end up in the path of the upstream request.
+	if _, err := strconv.ParseUint(suburbID, 10, 64); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid suburb ID %q", suburbID))
+		return
+	}
+
+	var stages []eskomlol.Stage
+	for _, value := range strings.Split(r.URL.Query().Get("stage"), ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		stage, err := eskomlol.ParseStage(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		stages = append(stages, stage)
+	}
+
+	if len(stages) == 0 {
+		stage, err := s.client.Status(r.Context())
+		if err != nil {
+			writeUpstreamError(w, err)
+			return
+		}
+		if stage < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("there is currently no loadshedding, the stage parameter is required"))
+			return
+		}
+		stages = []eskomlol.Stage{stage}
+	}
+
+	result, err := s.client.Schedule(r.Context(), suburbID, stages...)
+	if len(result) == 0 && err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	schedules := Schedules{SuburbID: suburbID, Schedules: make([]eskomlol.Schedule, 0, len(result))}
+	for _, schedule := range result {
+		schedules.Schedules = append(schedules.Schedules, schedule)
+	}
+	sort.Slice(schedules.Schedules, func(i, j int) bool {
+		return schedules.Schedules[i].Stage < schedules.Schedules[j].Stage
+	})
+
+	var scheduleErr *eskomlol.ScheduleError
+	if errors.As(err, &scheduleErr) {
+		schedules.Errors = make(map[string]string)
+		for stage, stageErr := range scheduleErr.Errors {
+			schedules.Errors[stage.Name()] = stageErr.Error()
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, schedules)
+}
+
+// writeJSON writes v as the JSON response body with an ETag, or a 304 response when the
+// request's If-None-Match header matches the ETag.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data = append(data, '\n')
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+
+	if status == http.StatusOK && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
+
+// etagMatches reports whether the If-None-Match header value matches etag, using the weak
+// comparison required for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || strings.TrimPrefix(value, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	data, _ := json.Marshal(Error{Error: err.Error()})
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// writeUpstreamError writes an error returned by the Client with a matching status code.
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, eskomlol.ErrInvalidStage), errors.Is(err, eskomlol.ErrInvalidProvince):
+		status = http.StatusBadRequest
+	case errors.Is(err, eskomlol.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	}
+
+	writeError(w, status, err)
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+	"github.com/teamjorge/eskomlol/eskomtest"
+)
+
+type testServer struct {
+	*httptest.Server
+	upstream *eskomtest.Server
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	upstream := eskomtest.NewServer(eskomtest.DefaultDataset(time.Now()))
+	t.Cleanup(upstream.Close)
+
+	client := upstream.NewClient(
+		eskomlol.WithCache(eskomlol.NewMemoryCache(100)),
+		eskomlol.WithRetryPolicy(eskomlol.RetryPolicy{}),
+	)
+	ts := &testServer{Server: httptest.NewServer(New(client)), upstream: upstream}
+	t.Cleanup(ts.Server.Close)
+
+	return ts
+}
+
+func get(t *testing.T, url string, header http.Header, out interface{}) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != nil && res.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+	}
+
+	return res
+}
+
+func TestStatus(t *testing.T) {
+	ts := newTestServer(t)
+	ts.upstream.SetStage(4)
+
+	var status Status
+	res := get(t, ts.URL+"/status", nil, &status)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code %d", res.StatusCode)
+	}
+	if status != (Status{Stage: 4, Level: 4, Loadshedding: true}) {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	res = get(t, ts.URL+"/status", http.Header{"If-None-Match": {`"other", W/` + etag}}, nil)
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", res.StatusCode)
+	}
+
+	// The second request is served from the cache of the Client.
+	if requests := ts.upstream.Requests(eskomtest.Status); requests != 1 {
+		t.Errorf("expected 1 upstream request, got %d", requests)
+	}
+}
+
+func TestMunicipalities(t *testing.T) {
+	ts := newTestServer(t)
+
+	var municipalities []Municipality
+	res := get(t, ts.URL+"/provinces/western-cape/municipalities", nil, &municipalities)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code %d", res.StatusCode)
+	}
+	if len(municipalities) != 1 || municipalities[0] != (Municipality{ID: "336", Name: "Stellenbosch"}) {
+		t.Errorf("unexpected municipalities %+v", municipalities)
+	}
+
+	if res := get(t, ts.URL+"/provinces/atlantis/municipalities", nil, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid province, got %d", res.StatusCode)
+	}
+
+	var provinces []Province
+	get(t, ts.URL+"/provinces", nil, &provinces)
+	if len(provinces) != 9 {
+		t.Errorf("expected 9 provinces, got %d", len(provinces))
+	}
+}
+
+func TestSuburbs(t *testing.T) {
+	ts := newTestServer(t)
+
+	var suburbs Suburbs
+	get(t, ts.URL+"/municipalities/166/suburbs?search=bry&page=1", nil, &suburbs)
+	expected := Suburbs{Page: 1, Total: 2, Results: []Suburb{
+		{ID: "1058852", Name: "Bryanston", Total: 555},
+		{ID: "1058853", Name: "Bryanston Ext 1", Total: 120},
+	}}
+	if !reflect.DeepEqual(suburbs, expected) {
+		t.Errorf("unexpected suburbs %+v", suburbs)
+	}
+
+	if res := get(t, ts.URL+"/municipalities/1/suburbs?page=x", nil, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid page, got %d", res.StatusCode)
+	}
+
+	var results []SearchResult
+	get(t, ts.URL+"/suburbs/search?q=bryanston&max=1", nil, &results)
+	if len(results) != 1 || results[0].ID != "1058852" || results[0].Province != "Gauteng" {
+		t.Errorf("unexpected search results %+v", results)
+	}
+
+	if res := get(t, ts.URL+"/suburbs/search", nil, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 without a search term, got %d", res.StatusCode)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	ts := newTestServer(t)
+	ts.upstream.FailWhen(eskomtest.Schedule, http.StatusNotFound, func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, "/GetScheduleM/1058852/3/")
+	})
+
+	var schedules Schedules
+	res := get(t, ts.URL+"/suburbs/1058852/schedule", nil, &schedules)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code %d", res.StatusCode)
+	}
+	if len(schedules.Schedules) != 1 || schedules.Schedules[0].Stage != 2 || len(schedules.Schedules[0].Times) == 0 {
+		t.Errorf("unexpected schedules %+v", schedules)
+	}
+
+	schedules = Schedules{}
+	get(t, ts.URL+"/suburbs/1058852/schedule?stage=4,3,2", nil, &schedules)
+	if len(schedules.Schedules) != 2 || schedules.Schedules[0].Stage != 2 || schedules.Schedules[1].Stage != 4 {
+		t.Errorf("unexpected schedules %+v", schedules)
+	}
+	if !strings.Contains(schedules.Errors["Stage 3"], "404") {
+		t.Errorf("expected an error for stage 3, got %v", schedules.Errors)
+	}
+
+	if res := get(t, ts.URL+"/suburbs/1058852/schedule?stage=9", nil, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid stage, got %d", res.StatusCode)
+	}
+	if res := get(t, ts.URL+"/suburbs/2/schedule?stage=2", nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown suburb, got %d", res.StatusCode)
+	}
+	if res := get(t, ts.URL+"/suburbs/1%3Fx=1/schedule?stage=2", nil, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-numeric suburb ID, got %d", res.StatusCode)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	ts := newTestServer(t)
+	ts.upstream.SetError(eskomtest.Status, http.StatusServiceUnavailable)
+
+	if res := get(t, ts.URL+"/healthz", nil, nil); res.StatusCode != http.StatusOK {
+		t.Errorf("expected healthz to succeed, got %d", res.StatusCode)
+	}
+	if res := get(t, ts.URL+"/readyz", nil, nil); res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected readyz to fail, got %d", res.StatusCode)
+	}
+	if res := get(t, ts.URL+"/status", nil, nil); res.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected 502 when Eskom is unavailable, got %d", res.StatusCode)
+	}
+	if res := get(t, ts.URL+"/nope", nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown path, got %d", res.StatusCode)
+	}
+
+	res, err := http.Post(ts.URL+"/status", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", res.StatusCode)
+	}
+}
@@ -1,6 +1,11 @@
 package eskomlol
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 type Stage int
 
@@ -16,6 +21,11 @@ func (s Stage) Name() string {
 	}
 }
 
+// String implements fmt.Stringer and returns the same value as Name.
+func (s Stage) String() string {
+	return s.Name()
+}
+
 // Valid determines if the stage is within the valid range.
 func (s Stage) Valid() bool {
 	var exists bool
@@ -28,6 +38,67 @@ func (s Stage) Valid() bool {
 	return exists
 }
 
+// MarshalText implements encoding.TextMarshaler and returns the Name of the stage.
+func (s Stage) MarshalText() ([]byte, error) {
+	if !s.Valid() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidStage, int(s))
+	}
+	return []byte(s.Name()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseStage.
+func (s *Stage) UnmarshalText(text []byte) error {
+	stage, err := ParseStage(string(text))
+	if err != nil {
+		return err
+	}
+	*s = stage
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler and encodes the stage as its Name.
+func (s Stage) MarshalJSON() ([]byte, error) {
+	text, err := s.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler and accepts either a number or any value accepted by ParseStage.
+func (s *Stage) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		var number int
+		if err := json.Unmarshal(data, &number); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidStage, string(data))
+		}
+		value = strconv.Itoa(number)
+	}
+	return s.UnmarshalText([]byte(value))
+}
+
+// ParseStage parses a stage from its name or number.
+//
+// Accepted values include "Stage 4", "stage4", "4", "No Loadshedding", "none" and "Unknown".
+func ParseStage(value string) (Stage, error) {
+	normalised := strings.ToLower(strings.TrimSpace(value))
+	switch normalised {
+	case "unknown":
+		return -1, nil
+	case "no loadshedding", "none":
+		return 0, nil
+	}
+
+	normalised = strings.TrimSpace(strings.TrimPrefix(normalised, "stage"))
+	number, err := strconv.Atoi(normalised)
+	if err != nil || !Stage(number).Valid() {
+		return -1, fmt.Errorf("%w: %q", ErrInvalidStage, value)
+	}
+
+	return Stage(number), nil
+}
+
 var stageMap map[int]Stage = map[int]Stage{
 	-1: -1,
 	1:  0,
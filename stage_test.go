@@ -1,6 +1,10 @@
 package eskomlol
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
 
 func TestStage(t *testing.T) {
 	s := stageMap[-1]
@@ -28,3 +32,71 @@ func TestStage(t *testing.T) {
 		t.Error("expected stage 8 to be valid")
 	}
 }
+
+func TestParseStage(t *testing.T) {
+	tests := map[string]Stage{
+		"Stage 4":         4,
+		"stage4":          4,
+		" 8 ":             8,
+		"0":               0,
+		"-1":              -1,
+		"No Loadshedding": 0,
+		"none":            0,
+		"Unknown":         -1,
+	}
+
+	for value, expected := range tests {
+		stage, err := ParseStage(value)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", value, err)
+			continue
+		}
+		if stage != expected {
+			t.Errorf("expected %q to be stage %d, got %d", value, expected, stage)
+		}
+	}
+
+	for _, value := range []string{"", "Stage 9", "stage -2", "lots"} {
+		if _, err := ParseStage(value); !errors.Is(err, ErrInvalidStage) {
+			t.Errorf("expected %q to fail with ErrInvalidStage, got %v", value, err)
+		}
+	}
+}
+
+func TestStageMarshalling(t *testing.T) {
+	if s := Stage(3).String(); s != "Stage 3" {
+		t.Errorf("expected String to be Stage 3, got %s", s)
+	}
+
+	data, err := json.Marshal(map[Stage]Stage{4: 4})
+	if err != nil {
+		t.Errorf("unexpected error marshalling stages: %v", err)
+		return
+	}
+	if string(data) != `{"Stage 4":"Stage 4"}` {
+		t.Errorf("expected stages to be encoded by name, got %s", string(data))
+	}
+
+	var decoded map[Stage]Stage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Errorf("unexpected error unmarshalling stages: %v", err)
+	}
+	if decoded[4] != 4 {
+		t.Errorf("expected stage 4 to round trip, got %v", decoded)
+	}
+
+	var stage Stage
+	if err := json.Unmarshal([]byte("6"), &stage); err != nil || stage != 6 {
+		t.Errorf("expected numeric JSON to be parsed as stage 6, got %d (%v)", stage, err)
+	}
+	if err := json.Unmarshal([]byte("true"), &stage); !errors.Is(err, ErrInvalidStage) {
+		t.Errorf("expected invalid JSON to fail with ErrInvalidStage, got %v", err)
+	}
+
+	if _, err := json.Marshal(Stage(42)); !errors.Is(err, ErrInvalidStage) {
+		t.Errorf("expected an invalid stage to fail with ErrInvalidStage, got %v", err)
+	}
+	if _, err := Stage(42).MarshalText(); !errors.Is(err, ErrInvalidStage) {
+		t.Errorf("expected an invalid stage to fail with ErrInvalidStage, got %v", err)
+	}
+}
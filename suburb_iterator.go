@@ -0,0 +1,181 @@
+package eskomlol
+
+import (
+	"context"
+	"sync"
+)
+
+// SuburbIterator walks every page of suburbs of a municipality, see Client.IterateSuburbs.
+//
+//	it := client.IterateSuburbs("166", "")
+//	for it.Next(ctx) {
+//		fmt.Println(it.Suburb().Name)
+//	}
+//	if err := it.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+type SuburbIterator struct {
+	client         *Client
+	municipalityID string
+	searchTerm     string
+
+	page    int
+	total   int
+	done    bool
+	buf     Suburbs
+	current Suburb
+	seen    map[string]bool
+	err     error
+
+	// limiter is waited on before requesting each page, in addition to the limiters of the Client.
+	limiter *RateLimiter
+}
+
+// IterateSuburbs returns a SuburbIterator over all suburbs of the given municipality and search term.
+//
+// Pages are requested as the iterator advances, until the pages cover the Total reported by the API
+// or a page is empty. Suburbs repeated on multiple pages are only returned once.
+func (c *Client) IterateSuburbs(municipalityID string, searchTerm string) *SuburbIterator {
+	return &SuburbIterator{
+		client:         c,
+		municipalityID: municipalityID,
+		searchTerm:     searchTerm,
+		page:           1,
+		seen:           make(map[string]bool),
+	}
+}
+
+// Next advances to the next suburb, requesting the next page if required. false is returned once
+// all suburbs were returned or a request failed, which is reported by Err.
+func (it *SuburbIterator) Next(ctx context.Context) bool {
+	for {
+		for len(it.buf) > 0 {
+			suburb := it.buf[0]
+			it.buf = it.buf[1:]
+			if it.seen[suburb.ID] {
+				continue
+			}
+			it.seen[suburb.ID] = true
+			it.current = suburb
+			return true
+		}
+
+		if it.done || it.err != nil {
+			return false
+		}
+
+		if it.limiter != nil {
+			if err := it.limiter.Wait(ctx); err != nil {
+				it.err = err
+				return false
+			}
+		}
+
+		res, err := it.client.Suburbs(ctx, it.municipalityID, it.searchTerm, it.page)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		// Pages may repeat suburbs, so the end is determined by the number of pages covering the Total.
+		it.done = len(res.Results) == 0 || it.page*suburbPageSize >= res.Total
+		it.page++
+		it.total = res.Total
+		it.buf = res.Results
+	}
+}
+
+// Suburb returns the current suburb.
+func (it *SuburbIterator) Suburb() Suburb {
+	return it.current
+}
+
+// Total returns the total number of suburbs reported by the API, once the first page was requested.
+func (it *SuburbIterator) Total() int {
+	return it.total
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *SuburbIterator) Err() error {
+	return it.err
+}
+
+// AllSuburbs returns every suburb of the given municipality and search term, without duplicates.
+//
+// The first page determines the total number of pages. With a concurrency above 1, the remaining
+// pages are requested concurrently with at most concurrency requests in flight. Suburbs are returned
+// in page order. When a page fails, the suburbs of the other pages are returned with the error.
+func (c *Client) AllSuburbs(ctx context.Context, municipalityID string, searchTerm string, concurrency int) (Suburbs, error) {
+	if concurrency <= 1 {
+		suburbs := make(Suburbs, 0)
+		it := c.IterateSuburbs(municipalityID, searchTerm)
+		for it.Next(ctx) {
+			suburbs = append(suburbs, it.Suburb())
+		}
+		return suburbs, it.Err()
+	}
+
+	first, err := c.Suburbs(ctx, municipalityID, searchTerm, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]Suburbs, (first.Total+suburbPageSize-1)/suburbPageSize)
+	if len(pages) == 0 || len(first.Results) == 0 {
+		return dedupeSuburbs(first.Results), nil
+	}
+	pages[0] = first.Results
+
+	errs := make([]error, len(pages))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for page := 2; page <= len(pages); page++ {
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[page-1] = ctx.Err()
+				return
+			}
+
+			res, err := c.Suburbs(ctx, municipalityID, searchTerm, page)
+			if err != nil {
+				errs[page-1] = err
+				return
+			}
+			pages[page-1] = res.Results
+		}(page)
+	}
+	wg.Wait()
+
+	suburbs := make(Suburbs, 0, first.Total)
+	for _, page := range pages {
+		suburbs = append(suburbs, page...)
+	}
+	suburbs = dedupeSuburbs(suburbs)
+
+	for _, err := range errs {
+		if err != nil {
+			return suburbs, err
+		}
+	}
+	return suburbs, nil
+}
+
+// dedupeSuburbs removes all but the first occurrence of each suburb ID.
+func dedupeSuburbs(suburbs Suburbs) Suburbs {
+	seen := make(map[string]bool, len(suburbs))
+	res := make(Suburbs, 0, len(suburbs))
+	for _, suburb := range suburbs {
+		if seen[suburb.ID] {
+			continue
+		}
+		seen[suburb.ID] = true
+		res = append(res, suburb)
+	}
+	return res
+}
@@ -0,0 +1,135 @@
+package eskomlol_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+	"github.com/teamjorge/eskomlol/eskomtest"
+)
+
+// newSuburbServer serves total suburbs for municipality 1 in pages, repeating the last suburb of
+// each page at the start of the next one. failPage responds to the given page with a server error.
+func newSuburbServer(t *testing.T, total int, failPage int) *eskomtest.Server {
+	t.Helper()
+
+	data := eskomtest.Dataset{Suburbs: map[string][]eskomtest.Suburb{"1": {}}}
+	for i := 0; i < total; i++ {
+		data.Suburbs["1"] = append(data.Suburbs["1"], eskomtest.Suburb{ID: strconv.Itoa(i), Name: "Suburb " + strconv.Itoa(i), Total: i})
+	}
+	server := eskomtest.NewServer(data)
+	t.Cleanup(server.Close)
+
+	server.SetLatency(5 * time.Millisecond)
+	server.SetPageOverlap(1)
+	if failPage > 0 {
+		server.FailWhen(eskomtest.Suburbs, http.StatusInternalServerError, func(r *http.Request) bool {
+			return r.URL.Query().Get("pageNum") == strconv.Itoa(failPage)
+		})
+	}
+
+	return server
+}
+
+func TestIterateSuburbs(t *testing.T) {
+	server := newSuburbServer(t, 250, 0)
+	c := server.NewClient()
+
+	it := c.IterateSuburbs("1", "")
+	var ids []string
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Suburb().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 250 {
+		t.Fatalf("expected 250 suburbs, got %d", len(ids))
+	}
+	for i, id := range ids {
+		if id != strconv.Itoa(i) {
+			t.Fatalf("expected suburb %d at index %d, got %s", i, i, id)
+		}
+	}
+	if it.Total() != 250 {
+		t.Errorf("expected a total of 250, got %d", it.Total())
+	}
+	if requests := server.Requests(eskomtest.Suburbs); requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+	if it.Next(context.Background()) {
+		t.Error("expected a finished iterator to stay finished")
+	}
+}
+
+func TestIterateSuburbsError(t *testing.T) {
+	server := newSuburbServer(t, 250, 2)
+	c := server.NewClient()
+
+	it := c.IterateSuburbs("1", "")
+	count := 0
+	for it.Next(context.Background()) {
+		count++
+	}
+	if count != 100 {
+		t.Errorf("expected the suburbs of the first page, got %d", count)
+	}
+	if !errors.Is(it.Err(), eskomlol.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", it.Err())
+	}
+}
+
+func TestAllSuburbs(t *testing.T) {
+	for _, concurrency := range []int{0, 3} {
+		server := newSuburbServer(t, 801, 0)
+		c := server.NewClient()
+
+		suburbs, err := c.AllSuburbs(context.Background(), "1", "", concurrency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(suburbs) != 801 {
+			t.Fatalf("concurrency %d: expected 801 suburbs, got %d", concurrency, len(suburbs))
+		}
+		for i, suburb := range suburbs {
+			if suburb.ID != strconv.Itoa(i) {
+				t.Fatalf("concurrency %d: expected suburb %d at index %d, got %s", concurrency, i, i, suburb.ID)
+			}
+		}
+		if requests := server.Requests(eskomtest.Suburbs); requests != 9 {
+			t.Errorf("concurrency %d: expected 9 requests, got %d", concurrency, requests)
+		}
+
+		expectedMax := 1
+		if concurrency > 1 {
+			expectedMax = concurrency
+		}
+		if maxInFlight := server.MaxInFlight(); maxInFlight > expectedMax {
+			t.Errorf("concurrency %d: expected at most %d requests in flight, got %d", concurrency, expectedMax, maxInFlight)
+		}
+	}
+}
+
+func TestAllSuburbsError(t *testing.T) {
+	server := newSuburbServer(t, 250, 3)
+	c := server.NewClient()
+
+	suburbs, err := c.AllSuburbs(context.Background(), "1", "", 2)
+	if !errors.Is(err, eskomlol.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+	if len(suburbs) != 200 {
+		t.Errorf("expected the suburbs of the first two pages, got %d", len(suburbs))
+	}
+
+	empty := newSuburbServer(t, 0, 0)
+	c = empty.NewClient()
+	if suburbs, err := c.AllSuburbs(context.Background(), "1", "", 2); err != nil || len(suburbs) != 0 {
+		t.Errorf("expected no suburbs, got %v (%v)", suburbs, err)
+	}
+}
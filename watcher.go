@@ -0,0 +1,232 @@
+package eskomlol
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatchInterval   = 5 * time.Minute
+	defaultWatchJitter     = 0.1
+	defaultWatchMaxBackoff = 30 * time.Minute
+	defaultWatchDebounce   = 2
+)
+
+// StageChange describes a transition between two loadshedding stages.
+type StageChange struct {
+	From Stage     `json:"from"`
+	To   Stage     `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// Watcher polls the loadshedding status and reports stage changes.
+//
+// The first reading establishes the current stage and is not reported as a change. A new stage
+// is only reported once it has been read a number of consecutive times (see WithWatchDebounce),
+// which prevents a flapping status from producing a burst of changes.
+type Watcher struct {
+	client     *Client
+	interval   time.Duration
+	jitter     float64
+	maxBackoff time.Duration
+	debounce   int
+	onChange   func(StageChange)
+	onError    func(error)
+	nowFunc    func() time.Time
+
+	mu      sync.Mutex
+	current Stage
+	known   bool
+	pending Stage
+	seen    int
+}
+
+type WatcherOpt func(*Watcher)
+
+// WithWatchInterval sets how often the status is polled. The default is 5 minutes, which is also
+// used when the interval is not positive.
+func WithWatchInterval(interval time.Duration) WatcherOpt {
+	return func(w *Watcher) {
+		w.interval = interval
+	}
+}
+
+// WithWatchJitter sets the fraction (0 to 1) of each polling interval that is randomised, so that
+// multiple watchers do not poll in lockstep. The default is 0.1.
+func WithWatchJitter(jitter float64) WatcherOpt {
+	return func(w *Watcher) {
+		w.jitter = jitter
+	}
+}
+
+// WithWatchMaxBackoff caps the polling interval while the status can not be retrieved.
+//
+// The interval doubles with every consecutive failure, up to this maximum. The default is 30 minutes.
+func WithWatchMaxBackoff(maxBackoff time.Duration) WatcherOpt {
+	return func(w *Watcher) {
+		w.maxBackoff = maxBackoff
+	}
+}
+
+// WithWatchDebounce sets the number of consecutive readings of a new stage required before it is
+// reported. A value of 1 reports every change immediately. The default is 2.
+func WithWatchDebounce(readings int) WatcherOpt {
+	return func(w *Watcher) {
+		w.debounce = readings
+	}
+}
+
+// WithOnChange sets a callback invoked for every stage change.
+//
+// The callback is invoked from the polling goroutine and delays the next poll until it returns.
+func WithOnChange(fn func(StageChange)) WatcherOpt {
+	return func(w *Watcher) {
+		w.onChange = fn
+	}
+}
+
+// WithOnError sets a callback invoked whenever the status could not be retrieved.
+func WithOnError(fn func(error)) WatcherOpt {
+	return func(w *Watcher) {
+		w.onError = fn
+	}
+}
+
+// NewWatcher creates a Watcher polling the status with the given Client.
+func NewWatcher(c *Client, opts ...WatcherOpt) *Watcher {
+	w := &Watcher{
+		client:     c,
+		interval:   defaultWatchInterval,
+		jitter:     defaultWatchJitter,
+		maxBackoff: defaultWatchMaxBackoff,
+		debounce:   defaultWatchDebounce,
+		nowFunc:    c.nowFunc,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if w.debounce < 1 {
+		w.debounce = 1
+	}
+	if w.interval <= 0 {
+		w.interval = defaultWatchInterval
+	}
+
+	return w
+}
+
+// Current returns the last reported stage. false is returned until the status has been read once.
+func (w *Watcher) Current() (Stage, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current, w.known
+}
+
+// Run polls the status until the context is done, invoking the WithOnChange callback for every
+// stage change. The context error is returned.
+func (w *Watcher) Run(ctx context.Context) error {
+	return w.run(ctx, func(StageChange) {})
+}
+
+// Watch starts polling the status in a new goroutine and returns a channel receiving every
+// stage change. The WithOnChange callback is still invoked. The channel is closed once the
+// context is done.
+//
+// The channel is unbuffered and polling pauses until each change is received.
+func (w *Watcher) Watch(ctx context.Context) <-chan StageChange {
+	changes := make(chan StageChange)
+
+	go func() {
+		defer close(changes)
+		w.run(ctx, func(change StageChange) {
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return changes
+}
+
+func (w *Watcher) run(ctx context.Context, emit func(StageChange)) error {
+	failures := 0
+	for {
+		stage, err := w.client.Status(ctx)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		if err != nil {
+			failures++
+			if w.onError != nil {
+				w.onError(err)
+			}
+		} else {
+			failures = 0
+			if change, ok := w.observe(stage); ok {
+				if w.onChange != nil {
+					w.onChange(change)
+				}
+				emit(change)
+			}
+		}
+
+		if err := sleepContext(ctx, w.delay(failures)); err != nil {
+			return err
+		}
+	}
+}
+
+// observe records a stage reading and reports if it completes a debounced change.
+func (w *Watcher) observe(stage Stage) (StageChange, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.known {
+		w.current, w.known = stage, true
+		return StageChange{}, false
+	}
+
+	if stage == w.current {
+		w.seen = 0
+		return StageChange{}, false
+	}
+
+	if stage != w.pending || w.seen == 0 {
+		w.pending, w.seen = stage, 0
+	}
+	w.seen++
+	if w.seen < w.debounce {
+		return StageChange{}, false
+	}
+
+	change := StageChange{From: w.current, To: stage, At: w.nowFunc()}
+	w.current, w.seen = stage, 0
+
+	return change, true
+}
+
+// delay calculates the time until the next poll after the given number of consecutive failures.
+func (w *Watcher) delay(failures int) time.Duration {
+	delay := w.interval
+	for i := 0; i < failures && delay < w.maxBackoff; i++ {
+		delay *= 2
+	}
+	if failures > 0 && w.maxBackoff > w.interval && delay > w.maxBackoff {
+		delay = w.maxBackoff
+	}
+
+	if w.jitter > 0 && delay > 0 {
+		jitter := w.jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= time.Duration(jitter * randFloat64() * float64(delay))
+	}
+
+	return delay
+}
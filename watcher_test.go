@@ -0,0 +1,170 @@
+package eskomlol_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+	"github.com/teamjorge/eskomlol/eskomtest"
+)
+
+var watcherTestNow = time.Date(2021, 11, 10, 12, 0, 0, 0, time.UTC)
+
+// newStatusServer reports the given stages in order, repeating the last one.
+func newStatusServer(t *testing.T, stages ...eskomlol.Stage) *eskomtest.Server {
+	t.Helper()
+
+	server := eskomtest.NewServer(eskomtest.Dataset{})
+	t.Cleanup(server.Close)
+	server.SetStageSequence(stages...)
+
+	return server
+}
+
+func newWatcherTestClient(server *eskomtest.Server) *eskomlol.Client {
+	return server.NewClient(
+		eskomlol.WithRetryPolicy(eskomlol.RetryPolicy{}),
+		eskomlol.WithNowFunc(func() time.Time { return watcherTestNow }),
+	)
+}
+
+func TestWatcherWatch(t *testing.T) {
+	// Stage 2, a flap to Stage 3, Stage 2 again and then a stable Stage 3.
+	server := newStatusServer(t, 2, 3, 2, 3, 3)
+
+	var callbacks int64
+	w := eskomlol.NewWatcher(
+		newWatcherTestClient(server),
+		eskomlol.WithWatchInterval(time.Millisecond),
+		eskomlol.WithWatchJitter(0),
+		eskomlol.WithOnChange(func(eskomlol.StageChange) { atomic.AddInt64(&callbacks, 1) }),
+	)
+
+	if _, ok := w.Current(); ok {
+		t.Error("expected no current stage before the first reading")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := w.Watch(ctx)
+
+	select {
+	case change := <-changes:
+		expected := eskomlol.StageChange{From: 2, To: 3, At: watcherTestNow}
+		if change != expected {
+			t.Errorf("expected %+v, got %+v", expected, change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a stage change")
+	}
+
+	if current, ok := w.Current(); !ok || current != 3 {
+		t.Errorf("expected current stage 3, got %v (%v)", current, ok)
+	}
+
+	// Allow a few more polls of the stable stage before shutting down.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	for change := range changes {
+		t.Errorf("unexpected stage change %+v", change)
+	}
+	if n := atomic.LoadInt64(&callbacks); n != 1 {
+		t.Errorf("expected 1 callback, got %d", n)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	// Stage 0, two server errors and then Stage 1.
+	server := newStatusServer(t, 0, 1)
+	var requests int64
+	server.FailWhen(eskomtest.Status, http.StatusInternalServerError, func(*http.Request) bool {
+		n := atomic.AddInt64(&requests, 1)
+		return n == 2 || n == 3
+	})
+
+	var (
+		mu      sync.Mutex
+		changes []eskomlol.StageChange
+		errs    int
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := eskomlol.NewWatcher(
+		newWatcherTestClient(server),
+		eskomlol.WithWatchInterval(time.Millisecond),
+		eskomlol.WithWatchMaxBackoff(4*time.Millisecond),
+		eskomlol.WithWatchDebounce(1),
+		eskomlol.WithOnChange(func(change eskomlol.StageChange) {
+			mu.Lock()
+			changes = append(changes, change)
+			mu.Unlock()
+			cancel()
+		}),
+		eskomlol.WithOnError(func(err error) {
+			if !errors.Is(err, eskomlol.ErrUnavailable) {
+				t.Errorf("expected ErrUnavailable, got %v", err)
+			}
+			mu.Lock()
+			errs++
+			mu.Unlock()
+		}),
+	)
+
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the watcher to stop")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if errs != 2 {
+		t.Errorf("expected 2 errors, got %d", errs)
+	}
+	if len(changes) != 1 || changes[0].From != 0 || changes[0].To != 1 {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+}
+
+func TestWatcherDelay(t *testing.T) {
+	w := eskomlol.NewWatcher(eskomlol.New(), eskomlol.WithWatchInterval(time.Minute), eskomlol.WithWatchJitter(0), eskomlol.WithWatchMaxBackoff(5*time.Minute))
+
+	tests := map[int]time.Duration{
+		0: time.Minute,
+		1: 2 * time.Minute,
+		2: 4 * time.Minute,
+		3: 5 * time.Minute,
+		9: 5 * time.Minute,
+	}
+	for failures, expected := range tests {
+		if delay := w.Delay(failures); delay != expected {
+			t.Errorf("%d failures: expected %s, got %s", failures, expected, delay)
+		}
+	}
+
+	w = eskomlol.NewWatcher(eskomlol.New(), eskomlol.WithWatchInterval(time.Minute), eskomlol.WithWatchJitter(0.5))
+	for i := 0; i < 100; i++ {
+		if delay := w.Delay(0); delay < 30*time.Second || delay > time.Minute {
+			t.Fatalf("jittered delay %s out of range", delay)
+		}
+	}
+
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		w := eskomlol.NewWatcher(eskomlol.New(), eskomlol.WithWatchInterval(interval), eskomlol.WithWatchJitter(0))
+		if delay := w.Delay(0); delay != eskomlol.DefaultWatchInterval {
+			t.Errorf("interval %s: expected the default delay, got %s", interval, delay)
+		}
+	}
+}
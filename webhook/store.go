@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/teamjorge/eskomlol/internal/fileutil"
+)
+
+// Store records which events were delivered to which webhooks, so that deliveries are not
+// repeated after a restart.
+//
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Seen reports whether key was marked as delivered.
+	Seen(key string) (bool, error)
+	// Mark records key as delivered at the given time.
+	Mark(key string, at time.Time) error
+	// Prune removes all deliveries marked before the given time.
+	Prune(before time.Time) error
+}
+
+// MemoryStore is a Store that only remembers deliveries for the lifetime of the process.
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[string]time.Time)}
+}
+
+// Seen reports whether key was marked as delivered.
+func (m *MemoryStore) Seen(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.keys[key]
+	return ok, nil
+}
+
+// Mark records key as delivered at the given time.
+func (m *MemoryStore) Mark(key string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[key] = at
+	return nil
+}
+
+// Prune removes all deliveries made before the given time.
+func (m *MemoryStore) Prune(before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prune(m.keys, before)
+	return nil
+}
+
+// FileStore is a Store persisting deliveries to a JSON file.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	keys map[string]time.Time
+}
+
+// NewFileStore creates a FileStore backed by the file at path, loading any deliveries
+// already recorded in it. The file is created on the first Mark.
+func NewFileStore(path string) (*FileStore, error) {
+	f := &FileStore{path: path, keys: make(map[string]time.Time)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &f.keys); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Seen reports whether key was marked as delivered.
+func (f *FileStore) Seen(key string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.keys[key]
+	return ok, nil
+}
+
+// Mark records key as delivered at the given time and writes the store to disk.
+func (f *FileStore) Mark(key string, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys[key] = at
+	return f.save()
+}
+
+// Prune removes all deliveries made before the given time and writes the store to disk when
+// any were removed.
+func (f *FileStore) Prune(before time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if prune(f.keys, before) == 0 {
+		return nil
+	}
+	return f.save()
+}
+
+func (f *FileStore) save() error {
+	data, err := json.Marshal(f.keys)
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFileAtomic(f.path, data)
+}
+
+// prune removes the keys marked before the given time and returns how many were removed.
+func prune(keys map[string]time.Time, before time.Time) int {
+	removed := 0
+	for key, at := range keys {
+		if at.Before(before) {
+			delete(keys, key)
+			removed++
+		}
+	}
+	return removed
+}
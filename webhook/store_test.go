@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Date(2021, 11, 10, 12, 0, 0, 0, time.UTC)
+
+	if seen, _ := s.Seen("a"); seen {
+		t.Error("expected a to be unseen")
+	}
+	s.Mark("a", now.Add(-time.Hour))
+	s.Mark("b", now)
+	if seen, _ := s.Seen("a"); !seen {
+		t.Error("expected a to be seen")
+	}
+
+	s.Prune(now.Add(-time.Minute))
+	if seen, _ := s.Seen("a"); seen {
+		t.Error("expected a to be pruned")
+	}
+	if seen, _ := s.Seen("b"); !seen {
+		t.Error("expected b to be kept")
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deliveries.json")
+	now := time.Date(2021, 11, 10, 12, 0, 0, 0, time.UTC)
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Mark("a", now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Mark("b", now); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if seen, _ := reopened.Seen(key); !seen {
+			t.Errorf("expected %s to survive a restart", key)
+		}
+	}
+
+	if err := reopened.Prune(now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen, _ := reopened.Seen("a"); seen {
+		t.Error("expected a to be pruned")
+	}
+}
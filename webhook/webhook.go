@@ -0,0 +1,507 @@
+// Package webhook delivers loadshedding notifications to webhooks.
+//
+// A Notifier posts an event whenever the loadshedding stage changes and shortly before the next
+// outage of each configured suburb. Event bodies are rendered with a text/template, signed with
+// HMAC-SHA256 and recorded in a Store, so that restarts do not deliver the same event twice.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+)
+
+const (
+	// SignatureHeader contains the HMAC-SHA256 signature of the body, formatted as "sha256=<hex>".
+	SignatureHeader = "X-Eskomlol-Signature"
+	// EventHeader contains the EventType of the delivered event.
+	EventHeader = "X-Eskomlol-Event"
+	// DeliveryHeader contains the ID of the delivered event.
+	DeliveryHeader = "X-Eskomlol-Delivery"
+
+	// DefaultTemplate renders the Event as JSON.
+	DefaultTemplate = "{{json .}}"
+
+	defaultLeadTime        = 30 * time.Minute
+	defaultCheckInterval   = time.Minute
+	defaultScheduleRefresh = time.Hour
+	defaultRetention       = 24 * time.Hour
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	// StageChanged is sent when the loadshedding stage changes.
+	StageChanged EventType = "stage_changed"
+	// OutageUpcoming is sent shortly before an outage of a suburb starts.
+	OutageUpcoming EventType = "outage_upcoming"
+)
+
+// Event is a single notification delivered to the webhooks.
+type Event struct {
+	// ID uniquely identifies the event and is used to prevent repeated deliveries.
+	ID          string                `json:"id"`
+	Type        EventType             `json:"type"`
+	At          time.Time             `json:"at"`
+	StageChange *eskomlol.StageChange `json:"stage_change,omitempty"`
+	Outage      *Outage               `json:"outage,omitempty"`
+}
+
+// Outage describes an upcoming outage of a suburb.
+type Outage struct {
+	SuburbID   string         `json:"suburb_id"`
+	SuburbName string         `json:"suburb_name,omitempty"`
+	Stage      eskomlol.Stage `json:"stage"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+}
+
+// Suburb is a suburb monitored for upcoming outages.
+type Suburb struct {
+	ID   string
+	Name string
+}
+
+// StageChangeEvent creates the Event delivered for a stage change.
+func StageChangeEvent(change eskomlol.StageChange) Event {
+	return Event{
+		ID:          fmt.Sprintf("stage/%d/%d/%d", change.From, change.To, change.At.Unix()),
+		Type:        StageChanged,
+		At:          change.At,
+		StageChange: &change,
+	}
+}
+
+// OutageEvent creates the Event delivered for an upcoming outage.
+func OutageEvent(outage Outage, at time.Time) Event {
+	return Event{
+		ID:     fmt.Sprintf("outage/%s/%d/%d", outage.SuburbID, outage.Stage, outage.Start.Unix()),
+		Type:   OutageUpcoming,
+		At:     at,
+		Outage: &outage,
+	}
+}
+
+// DeliveryError is returned when a webhook responds with an unsuccessful status code.
+type DeliveryError struct {
+	URL        string
+	StatusCode int
+	// RetryAfter is the delay requested by the Retry-After header, if present.
+	RetryAfter time.Duration
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("webhook %s responded with status %d", e.URL, e.StatusCode)
+}
+
+// RetryAfterDelay returns RetryAfter, implementing eskomlol.RetryAfterError.
+func (e *DeliveryError) RetryAfterDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// NewTemplate parses a template used to render event bodies.
+//
+// The template is executed with an Event and can use the json function to encode values,
+// for example {"text": "Loadshedding changed to {{.StageChange.To}}", "event": {{json .}}}.
+func NewTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// Sign returns the signature of body sent in the SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body. It can be used by
+// receivers to authenticate deliveries.
+func Verify(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Notifier delivers stage changes and upcoming outages to webhooks.
+type Notifier struct {
+	client          *eskomlol.Client
+	urls            []string
+	secret          []byte
+	template        *template.Template
+	httpClient      eskomlol.HttpClient
+	retryPolicy     eskomlol.RetryPolicy
+	store           Store
+	suburbs         []Suburb
+	leadTime        time.Duration
+	checkInterval   time.Duration
+	scheduleRefresh time.Duration
+	retention       time.Duration
+	watcherOpts     []eskomlol.WatcherOpt
+	onError         func(error)
+	nowFunc         func() time.Time
+
+	mu         sync.Mutex
+	schedules  map[string]eskomlol.Schedule
+	fetchedAt  time.Time
+	fetchedFor eskomlol.Stage
+}
+
+type Opt func(*Notifier)
+
+// WithSecret signs every delivery with the given secret.
+func WithSecret(secret []byte) Opt {
+	return func(n *Notifier) {
+		n.secret = secret
+	}
+}
+
+// WithTemplate renders event bodies with the given template, see NewTemplate.
+// DefaultTemplate is used when tmpl is nil.
+func WithTemplate(tmpl *template.Template) Opt {
+	return func(n *Notifier) {
+		if tmpl != nil {
+			n.template = tmpl
+		}
+	}
+}
+
+// WithHTTPClient sets the HttpClient used to deliver events.
+func WithHTTPClient(httpClient eskomlol.HttpClient) Opt {
+	return func(n *Notifier) {
+		n.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy sets how failed deliveries are retried. The default is eskomlol.DefaultRetryPolicy.
+//
+// When the policy has no Retryable function, transport errors, server errors, rate limiting and
+// timeouts are retried. A Retry-After header of a webhook is honoured like that of the Eskom API.
+func WithRetryPolicy(policy eskomlol.RetryPolicy) Opt {
+	return func(n *Notifier) {
+		n.retryPolicy = policy
+	}
+}
+
+// WithStore sets the Store recording deliveries. The default is a MemoryStore.
+func WithStore(store Store) Opt {
+	return func(n *Notifier) {
+		n.store = store
+	}
+}
+
+// WithSuburb monitors the given suburb for upcoming outages.
+func WithSuburb(id, name string) Opt {
+	return func(n *Notifier) {
+		n.suburbs = append(n.suburbs, Suburb{ID: id, Name: name})
+	}
+}
+
+// WithLeadTime sets how long before an outage starts it is notified. The default is 30 minutes.
+func WithLeadTime(leadTime time.Duration) Opt {
+	return func(n *Notifier) {
+		n.leadTime = leadTime
+	}
+}
+
+// WithCheckInterval sets how often upcoming outages are checked. The default is 1 minute, which
+// is also used when the interval is not positive.
+func WithCheckInterval(interval time.Duration) Opt {
+	return func(n *Notifier) {
+		n.checkInterval = interval
+	}
+}
+
+// WithScheduleRefresh sets how often schedules are fetched again, regardless of stage changes.
+// The default is 1 hour.
+func WithScheduleRefresh(refresh time.Duration) Opt {
+	return func(n *Notifier) {
+		n.scheduleRefresh = refresh
+	}
+}
+
+// WithRetention sets how long deliveries are kept in the Store once the lead time has passed.
+// Older deliveries are pruned by Run. The default is 1 day.
+//
+// Stage changes and outages are only notified once they have happened or are about to, so the
+// retention only needs to cover the length of an outage.
+func WithRetention(retention time.Duration) Opt {
+	return func(n *Notifier) {
+		n.retention = retention
+	}
+}
+
+// WithWatcherOpts configures the Watcher used to detect stage changes.
+func WithWatcherOpts(opts ...eskomlol.WatcherOpt) Opt {
+	return func(n *Notifier) {
+		n.watcherOpts = append(n.watcherOpts, opts...)
+	}
+}
+
+// WithOnError sets a callback invoked for errors encountered while running.
+func WithOnError(fn func(error)) Opt {
+	return func(n *Notifier) {
+		n.onError = fn
+	}
+}
+
+// New creates a Notifier delivering events to the given webhook URLs.
+func New(client *eskomlol.Client, urls []string, opts ...Opt) *Notifier {
+	n := &Notifier{
+		client:          client,
+		urls:            urls,
+		template:        template.Must(NewTemplate(DefaultTemplate)),
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		retryPolicy:     defaultRetryPolicy(),
+		store:           NewMemoryStore(),
+		leadTime:        defaultLeadTime,
+		checkInterval:   defaultCheckInterval,
+		scheduleRefresh: defaultScheduleRefresh,
+		retention:       defaultRetention,
+		nowFunc:         time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+	if n.checkInterval <= 0 {
+		n.checkInterval = defaultCheckInterval
+	}
+
+	return n
+}
+
+// Run watches for stage changes and upcoming outages until the context is done, which is
+// the error returned. Deliveries older than the retention are pruned from the Store on every
+// check.
+func (n *Notifier) Run(ctx context.Context) error {
+	watcherOpts := append([]eskomlol.WatcherOpt{}, n.watcherOpts...)
+	watcherOpts = append(watcherOpts, eskomlol.WithOnChange(func(change eskomlol.StageChange) {
+		n.handleError(n.Notify(ctx, StageChangeEvent(change)))
+	}))
+	if n.onError != nil {
+		watcherOpts = append(watcherOpts, eskomlol.WithOnError(n.onError))
+	}
+	watcher := eskomlol.NewWatcher(n.client, watcherOpts...)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watcher.Run(ctx)
+	}()
+	defer func() { <-done }()
+
+	n.prune()
+	ticker := time.NewTicker(n.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			n.prune()
+			if stage, ok := watcher.Current(); ok && len(n.suburbs) > 0 {
+				n.handleError(n.CheckOutages(ctx, stage))
+			}
+		}
+	}
+}
+
+// prune removes deliveries older than the lead time and retention from the Store.
+func (n *Notifier) prune() {
+	before := n.nowFunc().Add(-n.leadTime - n.retention)
+	if err := n.store.Prune(before); err != nil {
+		n.handleError(fmt.Errorf("pruning deliveries: %w", err))
+	}
+}
+
+// CheckOutages notifies the next outage of each suburb at the given stage if it starts
+// within the lead time. Schedules are only fetched when the stage changed or the refresh
+// interval passed since they were last fetched.
+func (n *Notifier) CheckOutages(ctx context.Context, stage eskomlol.Stage) error {
+	if stage < 1 {
+		return nil
+	}
+
+	now := n.nowFunc()
+	schedules, err := n.schedulesFor(ctx, stage, now)
+
+	var errs []string
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	for _, suburb := range n.suburbs {
+		schedule, ok := schedules[suburb.ID]
+		if !ok {
+			continue
+		}
+		next, ok := schedule.Next(now)
+		if !ok || next.Start.Sub(now) > n.leadTime {
+			continue
+		}
+
+		outage := Outage{
+			SuburbID:   suburb.ID,
+			SuburbName: suburb.Name,
+			Stage:      stage,
+			Start:      next.Start,
+			End:        next.End,
+		}
+		if err := n.Notify(ctx, OutageEvent(outage, now)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// schedulesFor returns the schedules of all suburbs at the given stage, fetching them if required.
+func (n *Notifier) schedulesFor(ctx context.Context, stage eskomlol.Stage, now time.Time) (map[string]eskomlol.Schedule, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.schedules != nil && n.fetchedFor == stage && now.Sub(n.fetchedAt) < n.scheduleRefresh {
+		return n.schedules, nil
+	}
+
+	schedules := make(map[string]eskomlol.Schedule)
+	var errs []string
+	for _, suburb := range n.suburbs {
+		result, err := n.client.Schedule(ctx, suburb.ID, stage)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("suburb %s: %v", suburb.ID, err))
+			continue
+		}
+		schedules[suburb.ID] = result[stage]
+	}
+
+	if len(errs) > 0 {
+		// Keep retrying failed suburbs on the next check.
+		return schedules, errors.New(strings.Join(errs, "; "))
+	}
+
+	n.schedules, n.fetchedAt, n.fetchedFor = schedules, now, stage
+	return schedules, nil
+}
+
+// Notify delivers the event to every webhook that has not received it yet.
+func (n *Notifier) Notify(ctx context.Context, event Event) error {
+	var body bytes.Buffer
+	if err := n.template.Execute(&body, event); err != nil {
+		return fmt.Errorf("rendering webhook template: %w", err)
+	}
+
+	var errs []string
+	for _, url := range n.urls {
+		key := event.ID + " " + url
+		seen, err := n.store.Seen(key)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if seen {
+			continue
+		}
+
+		if err := n.deliver(ctx, url, event, body.Bytes()); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := n.store.Mark(key, n.nowFunc()); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("delivering %s: %s", event.ID, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// deliver posts the body to url, retrying according to the RetryPolicy.
+func (n *Notifier) deliver(ctx context.Context, url string, event Event, body []byte) error {
+	policy := n.retryPolicy
+	policy.Retryable = n.retryable
+	return policy.Do(ctx, func() error {
+		return n.post(ctx, url, event, body)
+	})
+}
+
+func (n *Notifier) post(ctx context.Context, url string, event Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(DeliveryHeader, event.ID)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+
+	res, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &DeliveryError{
+			URL:        url,
+			StatusCode: res.StatusCode,
+			RetryAfter: eskomlol.ParseRetryAfter(res.Header.Get("Retry-After"), n.nowFunc()),
+		}
+	}
+	return nil
+}
+
+// retryable reports whether a failed delivery should be retried.
+func (n *Notifier) retryable(err error) bool {
+	if n.retryPolicy.Retryable != nil {
+		return n.retryPolicy.Retryable(err)
+	}
+
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.StatusCode == http.StatusRequestTimeout ||
+			deliveryErr.StatusCode == http.StatusTooManyRequests ||
+			deliveryErr.StatusCode >= 500
+	}
+	// Timeouts of the http.Client are retried, the context of the delivery is checked by
+	// RetryPolicy.Do.
+	return eskomlol.DefaultRetryable(err)
+}
+
+func defaultRetryPolicy() eskomlol.RetryPolicy {
+	policy := eskomlol.DefaultRetryPolicy()
+	// eskomlol.DefaultRetryable retries all errors other than API errors, including client errors of webhooks.
+	policy.Retryable = nil
+	return policy
+}
+
+func (n *Notifier) handleError(err error) {
+	if err != nil && n.onError != nil {
+		n.onError(err)
+	}
+}
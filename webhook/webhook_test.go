@@ -0,0 +1,349 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/eskomlol"
+)
+
+type receiver struct {
+	mu        sync.Mutex
+	bodies    []string
+	headers   []http.Header
+	failFirst int64
+	requests  int64
+}
+
+func newReceiver(t *testing.T, failFirst int64) (*receiver, *httptest.Server) {
+	t.Helper()
+
+	r := &receiver{failFirst: failFirst}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt64(&r.requests, 1) <= r.failFirst {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		r.mu.Lock()
+		r.bodies = append(r.bodies, string(body))
+		r.headers = append(r.headers, req.Header.Clone())
+		r.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	return r, server
+}
+
+func testRetryPolicy() eskomlol.RetryPolicy {
+	return eskomlol.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+}
+
+func TestNotify(t *testing.T) {
+	r, server := newReceiver(t, 2)
+	secret := []byte("s3cret")
+
+	n := New(eskomlol.New(), []string{server.URL}, WithSecret(secret), WithRetryPolicy(testRetryPolicy()))
+	change := eskomlol.StageChange{From: 2, To: 4, At: time.Date(2021, 11, 10, 12, 0, 0, 0, time.UTC)}
+	event := StageChangeEvent(change)
+
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	// A repeated event is not delivered again.
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.bodies) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(r.bodies))
+	}
+	if requests := atomic.LoadInt64(&r.requests); requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+
+	expected := `{"id":"stage/2/4/1636545600","type":"stage_changed","at":"2021-11-10T12:00:00Z","stage_change":{"from":"Stage 2","to":"Stage 4","at":"2021-11-10T12:00:00Z"}}`
+	if r.bodies[0] != expected {
+		t.Errorf("unexpected body:\n%s\nexpected:\n%s", r.bodies[0], expected)
+	}
+
+	headers := r.headers[0]
+	if !Verify(secret, []byte(r.bodies[0]), headers.Get(SignatureHeader)) {
+		t.Errorf("invalid signature %q", headers.Get(SignatureHeader))
+	}
+	if headers.Get(EventHeader) != string(StageChanged) || headers.Get(DeliveryHeader) != event.ID {
+		t.Errorf("unexpected headers %v", headers)
+	}
+}
+
+func TestNotifyTemplate(t *testing.T) {
+	r, server := newReceiver(t, 0)
+
+	tmpl, err := NewTemplate(`{"text": "Loadshedding changed to {{.StageChange.To}}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := New(eskomlol.New(), []string{server.URL}, WithTemplate(tmpl))
+
+	change := eskomlol.StageChange{From: 0, To: 2, At: time.Now()}
+	if err := n.Notify(context.Background(), StageChangeEvent(change)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.bodies) != 1 || r.bodies[0] != `{"text": "Loadshedding changed to Stage 2"}` {
+		t.Errorf("unexpected bodies %q", r.bodies)
+	}
+}
+
+func TestNotifyErrors(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	_, ok := newReceiver(t, 0)
+
+	store := NewMemoryStore()
+	n := New(eskomlol.New(), []string{server.URL, ok.URL}, WithStore(store), WithRetryPolicy(testRetryPolicy()))
+	event := StageChangeEvent(eskomlol.StageChange{From: 1, To: 2, At: time.Now()})
+
+	err := n.Notify(context.Background(), event)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("expected a delivery error, got %v", err)
+	}
+	// Client errors are not retried.
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+
+	// Only the successful webhook is recorded, the failed webhook is retried later.
+	if seen, _ := store.Seen(event.ID + " " + ok.URL); !seen {
+		t.Error("expected successful delivery to be recorded")
+	}
+	if seen, _ := store.Seen(event.ID + " " + server.URL); seen {
+		t.Error("expected failed delivery not to be recorded")
+	}
+
+	var deliveryErr *DeliveryError
+	if !errors.As(n.deliver(context.Background(), server.URL, event, nil), &deliveryErr) || deliveryErr.StatusCode != 400 {
+		t.Errorf("expected a DeliveryError, got %v", deliveryErr)
+	}
+}
+
+func TestCheckOutages(t *testing.T) {
+	scheduleData, err := os.ReadFile("../test_data/schedule.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scheduleRequests int64
+	eskom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/GetScheduleM/1/2/_/1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt64(&scheduleRequests, 1)
+		w.Write(scheduleData)
+	}))
+	defer eskom.Close()
+	client := eskomlol.New(eskomlol.WithBaseURL(eskom.URL))
+
+	schedules, err := client.Schedule(context.Background(), "1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := schedules[2].Times[3]
+
+	r, server := newReceiver(t, 0)
+	n := New(client, []string{server.URL}, WithSuburb("1", "Bryanston"), WithLeadTime(15*time.Minute))
+
+	now := item.Start.Add(-20 * time.Minute)
+	n.nowFunc = func() time.Time { return now }
+	if err := n.CheckOutages(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.bodies) != 0 {
+		t.Fatalf("expected no deliveries outside the lead time, got %d", len(r.bodies))
+	}
+
+	now = item.Start.Add(-10 * time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := n.CheckOutages(context.Background(), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(r.bodies) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(r.bodies))
+	}
+	if requests := atomic.LoadInt64(&scheduleRequests); requests != 2 {
+		t.Errorf("expected schedules to be reused, got %d schedule requests", requests)
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(r.bodies[0]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != OutageUpcoming || event.Outage == nil {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if !event.Outage.Start.Equal(item.Start) || event.Outage.SuburbName != "Bryanston" || event.Outage.Stage != 2 {
+		t.Errorf("unexpected outage %+v", event.Outage)
+	}
+
+	// Errors fetching schedules are returned.
+	if err := n.CheckOutages(context.Background(), 3); err == nil {
+		t.Error("expected an error for a missing schedule")
+	}
+}
+
+func TestRun(t *testing.T) {
+	var requests int64
+	eskom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt64(&requests, 1) == 1 {
+			w.Write([]byte("1"))
+			return
+		}
+		w.Write([]byte("3"))
+	}))
+	defer eskom.Close()
+
+	r, server := newReceiver(t, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n := New(
+		eskomlol.New(eskomlol.WithBaseURL(eskom.URL)),
+		[]string{server.URL},
+		WithWatcherOpts(eskomlol.WithWatchInterval(time.Millisecond), eskomlol.WithWatchDebounce(1)),
+	)
+	done := make(chan error)
+	go func() { done <- n.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.Lock()
+		delivered := len(r.bodies)
+		r.mu.Unlock()
+		if delivered > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a delivery")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(r.bodies[0], `"to":"Stage 2"`) {
+		t.Errorf("unexpected body %s", r.bodies[0])
+	}
+}
+
+func TestNewCheckInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		if n := New(eskomlol.New(), nil, WithCheckInterval(interval)); n.checkInterval != defaultCheckInterval {
+			t.Errorf("expected the default check interval for %s, got %s", interval, n.checkInterval)
+		}
+	}
+}
+
+func TestRunPrune(t *testing.T) {
+	eskom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("1"))
+	}))
+	defer eskom.Close()
+
+	now := time.Date(2021, 11, 10, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.Mark("old", now.Add(-defaultLeadTime-defaultRetention-time.Minute))
+	store.Mark("recent", now.Add(-defaultRetention))
+
+	n := New(eskomlol.New(eskomlol.WithBaseURL(eskom.URL)), nil, WithStore(store), WithCheckInterval(time.Millisecond))
+	n.nowFunc = func() time.Time { return now }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	n.Run(ctx)
+
+	if seen, _ := store.Seen("old"); seen {
+		t.Error("expected the old delivery to be pruned")
+	}
+	if seen, _ := store.Seen("recent"); !seen {
+		t.Error("expected the recent delivery to be kept")
+	}
+}
+
+func TestDeliverRetryAfter(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt64(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	event := StageChangeEvent(eskomlol.StageChange{From: 1, To: 2, At: time.Now()})
+
+	// A Retry-After beyond the maximum delay stops the retries.
+	policy := eskomlol.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	n := New(eskomlol.New(), []string{server.URL}, WithRetryPolicy(policy))
+	var deliveryErr *DeliveryError
+	if !errors.As(n.deliver(context.Background(), server.URL, event, nil), &deliveryErr) || deliveryErr.RetryAfter != time.Second {
+		t.Fatalf("expected a DeliveryError with a Retry-After of 1s, got %v", deliveryErr)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+
+	// Otherwise the requested delay is waited for.
+	atomic.StoreInt64(&requests, 0)
+	policy.MaxDelay = 0
+	n = New(eskomlol.New(), []string{server.URL}, WithRetryPolicy(policy))
+	start := time.Now()
+	if err := n.deliver(context.Background(), server.URL, event, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the delivery to wait for the Retry-After, took %s", elapsed)
+	}
+}
+
+func TestDeliverClientTimeout(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt64(&requests, 1) == 1 {
+			select {
+			case <-req.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}))
+	defer server.Close()
+
+	httpClient := server.Client()
+	httpClient.Timeout = 20 * time.Millisecond
+	n := New(eskomlol.New(), []string{server.URL}, WithHTTPClient(httpClient), WithRetryPolicy(testRetryPolicy()))
+	event := StageChangeEvent(eskomlol.StageChange{From: 1, To: 2, At: time.Now()})
+
+	if err := n.deliver(context.Background(), server.URL, event, nil); err != nil {
+		t.Errorf("expected the timed out delivery to be retried, got %v", err)
+	}
+	if count := atomic.LoadInt64(&requests); count != 2 {
+		t.Errorf("expected 2 requests, got %d", count)
+	}
+}